@@ -0,0 +1,82 @@
+package main
+
+import (
+	"PetProjectGo/internal/config"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/storage/mongodb"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"os"
+)
+
+// runCommand runs a maintenance command instead of the server:
+//
+//	repair [-dry-run]  fix users that are only in Mongo or only in Postgres
+//	check-logins       list logins that conflict ignoring case; exits with 1 if any
+func runCommand(
+	logger *logging.Logger,
+	cfg *config.Config,
+	mongo *mongodb.MongoDB,
+	postgres *sqlx.DB,
+	name string,
+	args []string,
+) {
+	switch name {
+	case "repair":
+		repair(logger, cfg, mongo, postgres, args)
+	case "check-logins":
+		checkLogins(logger, cfg, mongo, postgres)
+	default:
+		logger.Fatal("Unknown command", zap.String("command", name))
+	}
+}
+
+func repair(logger *logging.Logger, cfg *config.Config, mongo *mongodb.MongoDB, postgres *sqlx.DB, args []string) {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would be fixed")
+	_ = flags.Parse(args)
+
+	registrations, err := services.NewRegistrationService(logger, &cfg.App, mongo, postgres)
+	if err != nil {
+		logger.Fatal("Error creating registration service", zap.Error(err))
+	}
+
+	report, err := registrations.Repair(*dryRun)
+	if err != nil {
+		logger.Fatal("Repair failed", zap.Error(err))
+	}
+
+	printReport(report)
+}
+
+func checkLogins(logger *logging.Logger, cfg *config.Config, mongo *mongodb.MongoDB, postgres *sqlx.DB) {
+	registrations, err := services.NewRegistrationService(logger, &cfg.App, mongo, postgres)
+	if err != nil {
+		logger.Fatal("Error creating registration service", zap.Error(err))
+	}
+
+	report, err := registrations.CheckLogins()
+	if err != nil {
+		logger.Fatal("Checking logins failed", zap.Error(err))
+	}
+
+	printReport(report)
+
+	if len(report.Conflicts) > 0 || len(report.NotNormalized) > 0 {
+		mongo.Disconnect()
+		os.Exit(1)
+	}
+}
+
+func printReport(report any) {
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(string(out))
+}
@@ -64,6 +64,11 @@ func main() {
 		logger.Error("Postgres migration error", zap.Error(err))
 	}
 
+	if len(os.Args) > 1 {
+		runCommand(logger, cfg, mongoDB, postgresDB, os.Args[1], os.Args[2:])
+		return
+	}
+
 	srv, err := server.NewWebServer(logger, cfg, mongoDB, postgresDB)
 	if err != nil {
 		logger.Error("Error creating server", zap.Error(err))
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"github.com/spf13/viper"
+	"net"
+	"strings"
 	"time"
 )
 
@@ -12,13 +14,112 @@ type Config struct {
 	App      AppConfig                `mapstructure:"app"`
 	Postgres PostgresConnectionConfig `mapstructure:"postgresRepo"`
 	Mongo    MongoDBConnectionConfig  `mapstructure:"mongoRepo"`
+	Mail     MailConfig               `mapstructure:"mail"`
 }
 
 type AppConfig struct {
-	SecretKeyToken                    string        `mapstructure:"secret_key_token"`
-	TokenExpirationTimeMinutes        time.Duration `mapstructure:"token_expiration_time_minutes"`
-	RefreshTokenExpirationTimeMinutes time.Duration `mapstructure:"refresh_token_expiration_time_minutes"`
-	PasswordMinLength                 int           `mapstructure:"password_min_length"`
+	SecretKeyToken                    string               `mapstructure:"secret_key_token"`
+	TokenExpirationTimeMinutes        time.Duration        `mapstructure:"token_expiration_time_minutes"`
+	RefreshTokenExpirationTimeMinutes time.Duration        `mapstructure:"refresh_token_expiration_time_minutes"`
+	PasswordMinLength                 int                  `mapstructure:"password_min_length"`
+	PasswordPolicy                    PasswordPolicyConfig `mapstructure:"password_policy"`
+	PasswordHash                      PasswordHashConfig   `mapstructure:"password_hash"`
+	PasswordResetTokenTTL             time.Duration        `mapstructure:"password_reset_token_ttl"`
+	PasswordResetInterval             time.Duration        `mapstructure:"password_reset_interval"`
+	EmailVerificationRequired         bool                 `mapstructure:"email_verification_required"`
+	EmailVerificationTokenTTL         time.Duration        `mapstructure:"email_verification_token_ttl"`
+	EmailVerificationResendInterval   time.Duration        `mapstructure:"email_verification_resend_interval"`
+	PublicURL                         string               `mapstructure:"public_url"`
+	TwoFactorIssuer                   string               `mapstructure:"two_factor_issuer"`
+	TwoFactorTokenTTL                 time.Duration        `mapstructure:"two_factor_token_ttl"`
+	AccountDeletionGracePeriod        time.Duration        `mapstructure:"account_deletion_grace_period"`
+	AccountDeletionCheckInterval      time.Duration        `mapstructure:"account_deletion_check_interval"`
+	RegistrationOutboxInterval        time.Duration        `mapstructure:"registration_outbox_interval"`
+	Admin                             AdminConfig          `mapstructure:"admin"`
+	JWT                               JWTConfig            `mapstructure:"jwt"`
+	Lockout                           LockoutConfig        `mapstructure:"lockout"`
+	APIKeys                           APIKeyConfig         `mapstructure:"api_keys"`
+}
+
+// PasswordPolicyConfig holds the password rules besides the minimal length,
+// which is app.password_min_length. With bcrypt MaxLength is capped at 72
+// bytes, the part of a password bcrypt uses. HistorySize is how many of the
+// latest passwords, the current one included, may not be reused; 0 allows any.
+type PasswordPolicyConfig struct {
+	MaxLength     int  `mapstructure:"max_length"`
+	RequireUpper  bool `mapstructure:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol"`
+	RejectLogin   bool `mapstructure:"reject_login"`
+	RejectCommon  bool `mapstructure:"reject_common"`
+	HistorySize   int  `mapstructure:"history_size"`
+}
+
+// PasswordHashConfig selects how new passwords are hashed: "argon2id" or
+// "bcrypt". Stored hashes made otherwise are replaced on the next login.
+type PasswordHashConfig struct {
+	Algorithm         string `mapstructure:"algorithm"`
+	BcryptCost        int    `mapstructure:"bcrypt_cost"`
+	Argon2MemoryKiB   uint32 `mapstructure:"argon2_memory_kib"`
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+}
+
+// LockoutConfig limits failed logins. After DelayAfter failures every next
+// attempt has to wait BaseDelay, doubled per failure up to MaxDelay. After
+// MaxAttempts failures the login is locked for Duration. Failures older than
+// Window are forgotten. MaxAttemptsPerIP counts the failures per client IP;
+// behind a reverse proxy web.trusted_proxies must list it, or every client
+// shares the IP of the proxy.
+type LockoutConfig struct {
+	MaxAttempts      int           `mapstructure:"max_attempts"`
+	MaxAttemptsPerIP int           `mapstructure:"max_attempts_per_ip"`
+	DelayAfter       int           `mapstructure:"delay_after"`
+	BaseDelay        time.Duration `mapstructure:"base_delay"`
+	MaxDelay         time.Duration `mapstructure:"max_delay"`
+	Window           time.Duration `mapstructure:"window"`
+	Duration         time.Duration `mapstructure:"duration"`
+}
+
+// APIKeyConfig limits API keys. A key expires after DefaultTTL unless a
+// shorter or longer lifetime, up to MaxTTL, is asked for. Its last use is
+// recorded at most once per LastUsedInterval.
+type APIKeyConfig struct {
+	DefaultTTL       time.Duration `mapstructure:"default_ttl"`
+	MaxTTL           time.Duration `mapstructure:"max_ttl"`
+	MaxPerUser       int           `mapstructure:"max_per_user"`
+	LastUsedInterval time.Duration `mapstructure:"last_used_interval"`
+}
+
+type JWTConfig struct {
+	Issuer              string        `mapstructure:"issuer"`
+	Audience            []string      `mapstructure:"audience"`
+	Leeway              time.Duration `mapstructure:"leeway"`
+	Algorithm           string        `mapstructure:"algorithm"`
+	KeyRotationInterval time.Duration `mapstructure:"key_rotation_interval"`
+	KeyCheckInterval    time.Duration `mapstructure:"key_check_interval"`
+}
+
+type AdminConfig struct {
+	Login    string `mapstructure:"login"`
+	Password string `mapstructure:"password" json:"-"`
+}
+
+type MailConfig struct {
+	Driver    string     `mapstructure:"driver"`
+	From      string     `mapstructure:"from"`
+	OutboxDir string     `mapstructure:"outbox_dir"`
+	SMTP      SMTPConfig `mapstructure:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password" json:"-"`
+	// Timeout bounds the whole delivery of a message, from dialing on.
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 type Logger struct {
@@ -28,10 +129,44 @@ type Logger struct {
 	StructDateFormat string `mapstructure:"struct_date"`
 }
 
+// ServerConfig holds the HTTP server settings. TrustedProxies lists the
+// addresses, single IPs or CIDRs, of the reverse proxies in front of the
+// server. Only a request coming from one of them has its client IP taken
+// from X-Forwarded-For; with none the client IP is the peer address.
 type ServerConfig struct {
-	Address     string        `mapstructure:"address"`
-	Timeout     time.Duration `mapstructure:"timeout"`
-	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+	Address        string        `mapstructure:"address"`
+	Timeout        time.Duration `mapstructure:"timeout"`
+	IdleTimeout    time.Duration `mapstructure:"idle_timeout"`
+	TrustedProxies []string      `mapstructure:"trusted_proxies"`
+}
+
+// TrustedProxyNets parses TrustedProxies, a single IP being a network of
+// one address.
+func (c *ServerConfig) TrustedProxyNets() ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("config: web.trusted_proxies: invalid address %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("config: web.trusted_proxies: invalid network %q", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
 }
 
 type MongoDBConnectionConfig struct {
@@ -69,9 +204,40 @@ func InitConfiguration(pathToConfig string) (*Config, error) {
 		return nil, err
 	}
 
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+// validate rejects the values the server cannot run with, such as the
+// intervals of the background loops, which must be positive for their tickers.
+func (c *Config) validate() error {
+	intervals := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"app.account_deletion_check_interval", c.App.AccountDeletionCheckInterval},
+		{"app.registration_outbox_interval", c.App.RegistrationOutboxInterval},
+		{"app.jwt.key_check_interval", c.App.JWT.KeyCheckInterval},
+		{"app.jwt.key_rotation_interval", c.App.JWT.KeyRotationInterval},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("config: %s must be positive, got %s", interval.key, interval.value)
+		}
+	}
+
+	_, err := c.Web.TrustedProxyNets()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func loadDefault() {
 	viper.SetDefault("log.path_info", "./logs/info.log")
 	viper.SetDefault("log.path_debug", "./logs/debug.log")
@@ -81,11 +247,64 @@ func loadDefault() {
 	viper.SetDefault("web.address", "127.0.0.1:8080")
 	viper.SetDefault("web.timeout", 10*time.Second)
 	viper.SetDefault("web.idle_timeout", 60*time.Second)
+	viper.SetDefault("web.trusted_proxies", []string{})
 
 	viper.SetDefault("app.password_min_length", 8)
+	viper.SetDefault("app.password_policy.max_length", 72)
+	viper.SetDefault("app.password_hash.algorithm", "argon2id")
+	viper.SetDefault("app.password_hash.bcrypt_cost", 12)
+	viper.SetDefault("app.password_hash.argon2_memory_kib", 19*1024)
+	viper.SetDefault("app.password_hash.argon2_iterations", 2)
+	viper.SetDefault("app.password_hash.argon2_parallelism", 1)
+	viper.SetDefault("app.password_policy.require_upper", true)
+	viper.SetDefault("app.password_policy.require_lower", true)
+	viper.SetDefault("app.password_policy.require_digit", true)
+	viper.SetDefault("app.password_policy.require_symbol", false)
+	viper.SetDefault("app.password_policy.reject_login", true)
+	viper.SetDefault("app.password_policy.reject_common", true)
+	viper.SetDefault("app.password_policy.history_size", 5)
 	viper.SetDefault("app.secret_key_token", "secret_key_token")
 	viper.SetDefault("app.token_expiration_time_minutes", 5)
 	viper.SetDefault("app.refresh_token_expiration_time_minutes", 24*60)
+	viper.SetDefault("app.password_reset_token_ttl", time.Hour)
+	viper.SetDefault("app.password_reset_interval", time.Minute)
+	viper.SetDefault("app.email_verification_required", false)
+	viper.SetDefault("app.email_verification_token_ttl", 24*time.Hour)
+	viper.SetDefault("app.email_verification_resend_interval", time.Minute)
+	viper.SetDefault("app.public_url", "http://127.0.0.1:8080")
+	viper.SetDefault("app.two_factor_issuer", "PetProjectGo")
+	viper.SetDefault("app.two_factor_token_ttl", 5*time.Minute)
+	viper.SetDefault("app.account_deletion_grace_period", 14*24*time.Hour)
+	viper.SetDefault("app.account_deletion_check_interval", time.Hour)
+	viper.SetDefault("app.registration_outbox_interval", 10*time.Second)
+	viper.SetDefault("app.api_keys.default_ttl", 90*24*time.Hour)
+	viper.SetDefault("app.api_keys.max_ttl", 365*24*time.Hour)
+	viper.SetDefault("app.api_keys.max_per_user", 10)
+	viper.SetDefault("app.api_keys.last_used_interval", time.Minute)
+	viper.SetDefault("app.admin.login", "")
+	viper.SetDefault("app.admin.password", "")
+	viper.SetDefault("app.jwt.issuer", "pet-server")
+	viper.SetDefault("app.jwt.audience", []string{"pet-server"})
+	viper.SetDefault("app.jwt.leeway", 30*time.Second)
+	viper.SetDefault("app.jwt.algorithm", "RS256")
+	viper.SetDefault("app.jwt.key_rotation_interval", 30*24*time.Hour)
+	viper.SetDefault("app.jwt.key_check_interval", time.Minute)
+	viper.SetDefault("app.lockout.max_attempts", 5)
+	viper.SetDefault("app.lockout.max_attempts_per_ip", 50)
+	viper.SetDefault("app.lockout.delay_after", 3)
+	viper.SetDefault("app.lockout.base_delay", time.Second)
+	viper.SetDefault("app.lockout.max_delay", 30*time.Second)
+	viper.SetDefault("app.lockout.window", 15*time.Minute)
+	viper.SetDefault("app.lockout.duration", 15*time.Minute)
+
+	viper.SetDefault("mail.driver", "file")
+	viper.SetDefault("mail.from", "no-reply@localhost")
+	viper.SetDefault("mail.outbox_dir", "./outbox")
+	viper.SetDefault("mail.smtp.host", "localhost")
+	viper.SetDefault("mail.smtp.port", 587)
+	viper.SetDefault("mail.smtp.username", "")
+	viper.SetDefault("mail.smtp.password", "")
+	viper.SetDefault("mail.smtp.timeout", 10*time.Second)
 
 	viper.SetDefault("mongoRepo.host", "localhost")
 	viper.SetDefault("mongoRepo.port", 27018)
@@ -0,0 +1,21 @@
+package models
+
+// API key scopes name the route groups a key may call. Requests made with a
+// session are not limited by scopes.
+const (
+	ScopeUser     = "user"
+	ScopeCategory = "category"
+	ScopeProduct  = "product"
+	ScopeAdmin    = "admin"
+)
+
+var Scopes = []string{ScopeUser, ScopeCategory, ScopeProduct, ScopeAdmin}
+
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
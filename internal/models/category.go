@@ -1,6 +1,11 @@
 package models
 
+// Category is a node of the category tree. Ancestors holds the guids of the
+// categories above it, from the root down to ParentGuid; root categories
+// have neither.
 type Category struct {
-	GUID string `bson:"guid,omitempty" json:"id,omitempty"`
-	Name string `bson:"name,omitempty" json:"name,omitempty"`
+	GUID       string   `bson:"guid,omitempty" json:"id,omitempty"`
+	Name       string   `bson:"name,omitempty" json:"name,omitempty"`
+	ParentGuid string   `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Ancestors  []string `bson:"ancestors,omitempty" json:"-"`
 }
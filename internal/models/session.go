@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type Session struct {
+	GUID             string     `bson:"guid,omitempty" json:"id,omitempty"`
+	UserGuid         string     `bson:"user_id,omitempty" json:"-"`
+	Device           string     `bson:"device,omitempty" json:"device,omitempty"`
+	IP               string     `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent        string     `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	RefreshTokenHash string     `bson:"refresh_token_hash,omitempty" json:"-"`
+	CreatedAt        *time.Time `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	LastUsedAt       *time.Time `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	ExpiresAt        *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	Current          bool       `bson:"-" json:"current"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type SigningKey struct {
+	Kid        string     `bson:"kid,omitempty"`
+	Algorithm  string     `bson:"algorithm,omitempty"`
+	PrivateKey string     `bson:"private_key,omitempty"`
+	CreatedAt  *time.Time `bson:"created_at,omitempty"`
+	RetiredAt  *time.Time `bson:"retired_at,omitempty"`
+	ExpiresAt  *time.Time `bson:"expires_at,omitempty"`
+}
@@ -1,15 +1,75 @@
 package models
 
-import "time"
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleSeller   = "seller"
+	RoleCustomer = "customer"
+)
+
+var Roles = []string{RoleAdmin, RoleSeller, RoleCustomer}
+
+const (
+	LoginMinLength = 3
+	LoginMaxLength = 64
+)
 
 type User struct {
-	GUID         string     `bson:"guid,omitempty" json:"id,omitempty" mapstructure:"user_id"`
-	Login        string     `bson:"login,omitempty" json:"login,omitempty"`
-	Name         string     `bson:"name,omitempty" json:"name,omitempty"`
-	LastName     string     `bson:"last_name,omitempty" json:"last_name,omitempty"`
-	LastLoginAt  *time.Time `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
-	CreatedAt    *time.Time `bson:"created_at,omitempty" json:"created_at,omitempty"`
-	UpdatedAt    *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
-	RefreshToken string     `bson:"refresh_token,omitempty" json:"-"`
-	IsLogged     bool       `bson:"is_logged,omitempty" json:"is_logged,omitempty"`
+	GUID                string     `bson:"guid,omitempty" json:"id,omitempty" mapstructure:"user_id"`
+	Login               string     `bson:"login,omitempty" json:"login,omitempty"`
+	Email               string     `bson:"email,omitempty" json:"email,omitempty"`
+	EmailVerifiedAt     *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	Name                string     `bson:"name,omitempty" json:"name,omitempty"`
+	LastName            string     `bson:"last_name,omitempty" json:"last_name,omitempty"`
+	Role                string     `bson:"role,omitempty" json:"role,omitempty"`
+	LastLoginAt         *time.Time `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+	CreatedAt           *time.Time `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt           *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	DeletionScheduledAt *time.Time `bson:"deletion_scheduled_at,omitempty" json:"deletion_scheduled_at,omitempty"`
+}
+
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLogin brings a login to the form it is stored and looked up in:
+// NFKC normalized, without surrounding spaces. The case is kept; logins are
+// compared case-insensitively by the database.
+func NormalizeLogin(login string) string {
+	return strings.TrimSpace(norm.NFKC.String(login))
+}
+
+// LoginKey returns the case folded form of a login, for comparing logins
+// outside of Mongo.
+func LoginKey(login string) string {
+	return strings.ToLower(NormalizeLogin(login))
+}
+
+// IsValidLogin reports whether a normalized login is between LoginMinLength
+// and LoginMaxLength characters and has no spaces or control characters.
+func IsValidLogin(login string) bool {
+	length := utf8.RuneCountInString(login)
+	if length < LoginMinLength || length > LoginMaxLength {
+		return false
+	}
+
+	for _, r := range login {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	return true
 }
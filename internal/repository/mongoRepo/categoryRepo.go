@@ -31,14 +31,37 @@ func NewCategoryRepoM(log *logging.Logger, mongo *mongodb.MongoDB, collection st
 	}
 }
 
+// legacyCategoryNameIndex is the index that kept category names unique
+// across the whole tree, before they were unique per parent.
+const legacyCategoryNameIndex = "name_1"
+
+// Codes of the errors of dropping an index that is not there.
+const (
+	codeNamespaceNotFound = 26
+	codeIndexNotFound     = 27
+)
+
 func (u *CategoryRepoM) CreateIndexesCategory() error {
 	const op = "CategoryRepoM.CreateIndexesCategory"
-	indexModel := mongo.IndexModel{
-		Keys:    bson.M{"name": 1},
-		Options: options.Index().SetUnique(true),
+
+	indexes := u.mongo.GetCollection(u.collection).Indexes()
+	_, err := indexes.DropOne(context.TODO(), legacyCategoryNameIndex)
+	var commandError mongo.CommandError
+	if err != nil && !(errors.As(err, &commandError) &&
+		(commandError.Code == codeIndexNotFound || commandError.Code == codeNamespaceNotFound)) {
+		u.log.Error("Error dropping index", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "parent_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.M{"ancestors": 1}},
 	}
 
-	_, err := u.mongo.GetCollection(u.collection).Indexes().CreateOne(context.TODO(), indexModel)
+	_, err = indexes.CreateMany(context.TODO(), indexModels)
 	if err != nil {
 		u.log.Error("Error creating indexes", zap.String("op", op), zap.Error(err))
 		return err
@@ -100,6 +123,72 @@ func (u *CategoryRepoM) GetByGuid(guid string) (*models.Category, error) {
 	return category, nil
 }
 
+// GetByGuids returns the categories with the given guids, in no particular order.
+func (u *CategoryRepoM) GetByGuids(guids []string) ([]*models.Category, error) {
+	const op = "CategoryRepoM.GetByGuids"
+
+	collection := u.mongo.GetCollection(u.collection)
+	cursor, err := collection.Find(context.TODO(), bson.M{"guid": bson.M{"$in": guids}})
+	if err != nil {
+		u.log.Error("Error getting categories", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	categories := []*models.Category{}
+	err = cursor.All(context.TODO(), &categories)
+	if err != nil {
+		u.log.Error("Error decoding categories", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// GetDescendantGuids returns the guids of every category below the category.
+func (u *CategoryRepoM) GetDescendantGuids(guid string) ([]string, error) {
+	const op = "CategoryRepoM.GetDescendantGuids"
+
+	collection := u.mongo.GetCollection(u.collection)
+	cursor, err := collection.Find(
+		context.TODO(),
+		bson.M{"ancestors": guid},
+		options.Find().SetProjection(bson.M{"guid": 1}),
+	)
+	if err != nil {
+		u.log.Error("Error getting descendant categories", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var categories []*models.Category
+	err = cursor.All(context.TODO(), &categories)
+	if err != nil {
+		u.log.Error("Error decoding categories", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	guids := make([]string, 0, len(categories))
+	for _, category := range categories {
+		guids = append(guids, category.GUID)
+	}
+
+	return guids, nil
+}
+
+func (u *CategoryRepoM) CountChildren(guid string) (int64, error) {
+	const op = "CategoryRepoM.CountChildren"
+
+	collection := u.mongo.GetCollection(u.collection)
+	count, err := collection.CountDocuments(context.TODO(), bson.M{"parent_id": guid})
+	if err != nil {
+		u.log.Error("Error counting child categories", zap.String("op", op), zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (u *CategoryRepoM) AddCategory(category *models.Category) error {
 	const op = "CategoryRepoM.AddCategory"
 
@@ -107,7 +196,7 @@ func (u *CategoryRepoM) AddCategory(category *models.Category) error {
 	_, err := collection.InsertOne(context.TODO(), category)
 	if err != nil {
 		var writeException mongo.WriteException
-		if errors.As(err, &writeException) {
+		if errors.As(err, &writeException) && mongo.IsDuplicateKeyError(err) {
 			return u.generateDuplicateErrorC(writeException)
 		}
 		u.log.Error("Error adding category", zap.String("op", op), zap.Error(err))
@@ -116,6 +205,147 @@ func (u *CategoryRepoM) AddCategory(category *models.Category) error {
 	return nil
 }
 
+// Rename changes the name of the category and returns the renamed category.
+func (u *CategoryRepoM) Rename(guid string, name string) (*models.Category, error) {
+	const op = "CategoryRepoM.Rename"
+	var category *models.Category
+
+	collection := u.mongo.GetCollection(u.collection)
+	err := collection.FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"guid": guid},
+		bson.M{"$set": bson.M{"name": name}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&category)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCategoryNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			u.log.Error("Category with duplicate name", zap.String("op", op), zap.Error(err))
+			return nil, DuplicateCategoryNameError
+		}
+		u.log.Error("Error renaming category", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// Move puts the category under another one, with the given ancestors, or
+// at the root when parentGuid is empty, and rewrites the ancestors of its
+// subtree to match. It returns the moved category.
+//
+// Mongo may run without a replica set, so the two writes are not in a
+// transaction: when the subtree cannot be rewritten, the move is undone.
+func (u *CategoryRepoM) Move(guid string, parentGuid string, ancestors []string) (*models.Category, error) {
+	const op = "CategoryRepoM.Move"
+
+	// The category is moved first: a name taken under the new parent stops
+	// the move before the subtree is touched.
+	previous, err := u.setParent(guid, parentGuid, ancestors)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.rewriteSubtree(guid, ancestors)
+	if err != nil {
+		u.log.Error("Error moving subcategories, undoing the move", zap.String("op", op), zap.Error(err))
+
+		// Rewriting the subtree with the previous ancestors also restores
+		// the descendants that were already moved.
+		_, err2 := u.setParent(guid, previous.ParentGuid, previous.Ancestors)
+		if err2 == nil {
+			err2 = u.rewriteSubtree(guid, previous.Ancestors)
+		}
+		if err2 != nil {
+			u.log.Error("Error undoing category move", zap.String("op", op), zap.String("guid", guid), zap.Error(err2))
+		}
+		return nil, err
+	}
+
+	previous.ParentGuid = parentGuid
+	previous.Ancestors = ancestors
+	return previous, nil
+}
+
+// setParent sets the parent and the ancestors of the category and returns
+// the category as it was before.
+func (u *CategoryRepoM) setParent(guid string, parentGuid string, ancestors []string) (*models.Category, error) {
+	const op = "CategoryRepoM.setParent"
+	var category *models.Category
+
+	update := bson.M{"$set": bson.M{"parent_id": parentGuid, "ancestors": ancestors}}
+	if parentGuid == "" {
+		update = bson.M{"$unset": bson.M{"parent_id": "", "ancestors": ""}}
+	}
+
+	collection := u.mongo.GetCollection(u.collection)
+	err := collection.FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"guid": guid},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&category)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCategoryNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			u.log.Error("Category with duplicate name", zap.String("op", op), zap.Error(err))
+			return nil, DuplicateCategoryNameError
+		}
+		u.log.Error("Error moving category", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// rewriteSubtree gives the descendants of the category the given ancestors
+// above it. The ancestors from the category down are kept, so it can be
+// repeated.
+func (u *CategoryRepoM) rewriteSubtree(guid string, ancestors []string) error {
+	prefix := bson.A{}
+	for _, ancestor := range ancestors {
+		prefix = append(prefix, ancestor)
+	}
+
+	collection := u.mongo.GetCollection(u.collection)
+	_, err := collection.UpdateMany(
+		context.TODO(),
+		bson.M{"ancestors": guid},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"ancestors": bson.M{"$concatArrays": bson.A{
+				prefix,
+				bson.M{"$slice": bson.A{
+					"$ancestors",
+					bson.M{"$indexOfArray": bson.A{"$ancestors", guid}},
+					bson.M{"$size": "$ancestors"},
+				}},
+			}},
+		}}}},
+	)
+
+	return err
+}
+
+func (u *CategoryRepoM) DeleteByGuid(guid string) error {
+	const op = "CategoryRepoM.DeleteByGuid"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.DeleteOne(context.TODO(), bson.M{"guid": guid})
+	if err != nil {
+		u.log.Error("Error deleting category", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrCategoryNotFound
+	}
+
+	return nil
+}
+
 func (u *CategoryRepoM) generateDuplicateErrorC(err mongo.WriteException) error {
 	const op = "CategoryRepoM.generateDuplicateErrorC"
 
@@ -127,11 +357,10 @@ func (u *CategoryRepoM) generateDuplicateErrorC(err mongo.WriteException) error
 			matches := re.FindStringSubmatch(we.Message)
 
 			if len(matches) > 1 {
-				errorMsg := fmt.Sprintf("%s: Duplicate key violation for index: %s", DuplicateCategoryNameError, matches[1])
-				return errors.New(errorMsg)
+				return fmt.Errorf("%w: Duplicate key violation for index: %s", DuplicateCategoryNameError, matches[1])
 			}
 		}
 	}
 
-	return nil
+	return DuplicateCategoryNameError
 }
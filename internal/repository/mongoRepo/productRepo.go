@@ -12,9 +12,62 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
 	"regexp"
+	"time"
 )
 
 var DuplicateProductNameError = fmt.Errorf("product with duplicate name")
+var ErrProductNotFound = fmt.Errorf("product not found")
+
+// Fields products can be sorted by.
+const (
+	ProductSortName      = "name"
+	ProductSortPrice     = "price"
+	ProductSortCreatedAt = "created_at"
+)
+
+// ProductQuery selects a page of products. Zero fields do not filter. The
+// products are sorted by SortField and then by guid, so that After, the sort
+// value and guid of the last product of the previous page, continues the
+// listing exactly where it stopped.
+type ProductQuery struct {
+	CategoryGuids []string
+	MinPrice      *int
+	MaxPrice      *int
+	InStock       bool
+	NamePrefix    string
+	SortField     string
+	Descending    bool
+	After         *ProductCursor
+	Limit         int
+}
+
+type ProductCursor struct {
+	Value interface{}
+	GUID  string
+}
+
+// productNameWeight is how much more a match in the name counts than one
+// in the description.
+const productNameWeight = 10
+
+// ProductSearchQuery selects the products matching a search. Text is a
+// $text search string, ranked by relevance; each of Patterns must match the
+// name or the description and none of Excluded may. Without Text the
+// products are ranked by the matches of Patterns, weighted like the index.
+type ProductSearchQuery struct {
+	Text         string
+	Patterns     []string
+	Excluded     []string
+	CategoryGuid string
+	Skip         int
+	Limit        int
+}
+
+// ProductSearchHit is a found product with its text search score.
+type ProductSearchHit struct {
+	models.Product `bson:",inline"`
+	Score          float64 `bson:"score"`
+}
 
 type ProductRepoM struct {
 	log        *logging.Logger
@@ -33,12 +86,28 @@ func NewProductRepoM(log *logging.Logger, mongo *mongodb.MongoDB, collection str
 func (u *ProductRepoM) CreateIndexesProduct() error {
 	const op = "ProductRepoM.CreateIndexesProduct"
 
-	indexModel := mongo.IndexModel{
-		Keys:    bson.M{"name": 1},
-		Options: options.Index().SetUnique(true),
+	// Besides the unique name and the guid, an index per sort field of
+	// ListProducts, with and without the category, ending with guid as the sort does.
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "guid", Value: 1}}},
+		{Keys: bson.D{{Key: "price", Value: 1}, {Key: "guid", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "guid", Value: 1}}},
+		{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "name", Value: 1}, {Key: "guid", Value: 1}}},
+		{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "price", Value: 1}, {Key: "guid", Value: 1}}},
+		{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "guid", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("product_text").
+				SetWeights(bson.M{"name": productNameWeight, "description": 1}),
+		},
 	}
 
-	_, err := u.mongo.GetCollection(u.collection).Indexes().CreateOne(context.TODO(), indexModel)
+	_, err := u.mongo.GetCollection(u.collection).Indexes().CreateMany(context.TODO(), indexModels)
 	if err != nil {
 		u.log.Error("Error creating indexes", zap.String("op", op), zap.Error(err))
 		return err
@@ -54,9 +123,8 @@ func (u *ProductRepoM) AddNewProduct(product *models.Product) error {
 	collection := u.mongo.GetCollection(u.collection)
 	_, err := collection.InsertOne(context.TODO(), product)
 	if err != nil {
-		var writeException mongo.WriteException
-		if errors.As(err, &writeException) {
-			return u.generateDuplicateErrorP(writeException)
+		if mongo.IsDuplicateKeyError(err) {
+			return u.generateDuplicateErrorP(err)
 		}
 		u.log.Error("Error adding product", zap.String("op", op), zap.Error(err))
 		return err
@@ -64,6 +132,239 @@ func (u *ProductRepoM) AddNewProduct(product *models.Product) error {
 	return nil
 }
 
+// ListProducts returns the page of products selected by the query.
+func (u *ProductRepoM) ListProducts(query *ProductQuery) ([]*models.Product, error) {
+	const op = "ProductRepoM.ListProducts"
+
+	filter := bson.M{}
+	switch len(query.CategoryGuids) {
+	case 0:
+	case 1:
+		filter["category_id"] = query.CategoryGuids[0]
+	default:
+		filter["category_id"] = bson.M{"$in": query.CategoryGuids}
+	}
+	price := bson.M{}
+	if query.MinPrice != nil {
+		price["$gte"] = *query.MinPrice
+	}
+	if query.MaxPrice != nil {
+		price["$lte"] = *query.MaxPrice
+	}
+	if len(price) > 0 {
+		filter["price"] = price
+	}
+	if query.InStock {
+		filter["quantity"] = bson.M{"$gt": 0}
+	}
+	if query.NamePrefix != "" {
+		// An anchored, case-sensitive prefix can use the name index.
+		filter["name"] = bson.M{"$regex": "^" + regexp.QuoteMeta(query.NamePrefix)}
+	}
+
+	direction, compare := 1, "$gt"
+	if query.Descending {
+		direction, compare = -1, "$lt"
+	}
+	if query.After != nil {
+		filter["$or"] = bson.A{
+			bson.M{query.SortField: bson.M{compare: query.After.Value}},
+			bson.M{query.SortField: query.After.Value, "guid": bson.M{compare: query.After.GUID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: query.SortField, Value: direction}, {Key: "guid", Value: direction}}).
+		SetLimit(int64(query.Limit))
+
+	collection := u.mongo.GetCollection(u.collection)
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		u.log.Error("Error listing products", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	products := []*models.Product{}
+	err = cursor.All(context.TODO(), &products)
+	if err != nil {
+		u.log.Error("Error decoding products", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// BackfillCreatedAt sets created_at of the products added before it was
+// stored, so that they can be sorted and paged by it.
+func (u *ProductRepoM) BackfillCreatedAt(timeNow *time.Time) error {
+	const op = "ProductRepoM.BackfillCreatedAt"
+
+	collection := u.mongo.GetCollection(u.collection)
+	_, err := collection.UpdateMany(
+		context.TODO(),
+		bson.M{"created_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"created_at": timeNow}},
+	)
+	if err != nil {
+		u.log.Error("Error backfilling product creation time", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// SearchProducts returns the products matching the search, the most
+// relevant first.
+func (u *ProductRepoM) SearchProducts(query *ProductSearchQuery) ([]*ProductSearchHit, error) {
+	const op = "ProductRepoM.SearchProducts"
+
+	filter := bson.M{}
+	if query.Text != "" {
+		filter["$text"] = bson.M{"$search": query.Text}
+	}
+	if query.CategoryGuid != "" {
+		filter["category_id"] = query.CategoryGuid
+	}
+	var and bson.A
+	for _, pattern := range query.Patterns {
+		and = append(and, bson.M{"$or": textFieldsMatch(pattern)})
+	}
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	var nor bson.A
+	for _, pattern := range query.Excluded {
+		nor = append(nor, textFieldsMatch(pattern)...)
+	}
+	if len(nor) > 0 {
+		filter["$nor"] = nor
+	}
+
+	collection := u.mongo.GetCollection(u.collection)
+	var cursor *mongo.Cursor
+	var err error
+	if query.Text != "" {
+		score := bson.M{"$meta": "textScore"}
+		opts := options.Find().
+			SetProjection(bson.M{"score": score}).
+			SetSort(bson.D{{Key: "score", Value: score}, {Key: "guid", Value: 1}}).
+			SetSkip(int64(query.Skip)).
+			SetLimit(int64(query.Limit))
+		cursor, err = collection.Find(context.TODO(), filter, opts)
+	} else {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: filter}},
+			{{Key: "$addFields", Value: bson.M{"score": patternsScore(query.Patterns)}}},
+			{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "guid", Value: 1}}}},
+			{{Key: "$skip", Value: query.Skip}},
+			{{Key: "$limit", Value: query.Limit}},
+		}
+		cursor, err = collection.Aggregate(context.TODO(), pipeline)
+	}
+	if err != nil {
+		u.log.Error("Error searching products", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	hits := []*ProductSearchHit{}
+	err = cursor.All(context.TODO(), &hits)
+	if err != nil {
+		u.log.Error("Error decoding products", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// patternsScore counts the matches of the patterns, those in the name
+// weighing productNameWeight.
+func patternsScore(patterns []string) bson.M {
+	matches := func(field string, pattern string) bson.M {
+		return bson.M{"$size": bson.M{"$regexFindAll": bson.M{
+			"input":   bson.M{"$ifNull": bson.A{"$" + field, ""}},
+			"regex":   pattern,
+			"options": "i",
+		}}}
+	}
+
+	terms := bson.A{}
+	for _, pattern := range patterns {
+		terms = append(terms,
+			bson.M{"$multiply": bson.A{productNameWeight, matches("name", pattern)}},
+			matches("description", pattern),
+		)
+	}
+
+	return bson.M{"$add": terms}
+}
+
+// textFieldsMatch matches a case-insensitive regular expression against the
+// searched fields.
+func textFieldsMatch(pattern string) bson.A {
+	regex := bson.M{"$regex": pattern, "$options": "i"}
+	return bson.A{bson.M{"name": regex}, bson.M{"description": regex}}
+}
+
+func (u *ProductRepoM) GetByGuid(guid string) (*models.Product, error) {
+	const op = "ProductRepoM.GetByGuid"
+	var product *models.Product
+
+	collection := u.mongo.GetCollection(u.collection)
+	err := collection.FindOne(context.TODO(), bson.M{"guid": guid}).Decode(&product)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrProductNotFound
+		}
+		u.log.Error("Error getting product by guid", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	return product, nil
+}
+
+// UpdateProduct sets the given fields of the product and returns the
+// updated product.
+func (u *ProductRepoM) UpdateProduct(guid string, set bson.M) (*models.Product, error) {
+	const op = "ProductRepoM.UpdateProduct"
+	var product *models.Product
+
+	collection := u.mongo.GetCollection(u.collection)
+	err := collection.FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"guid": guid},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&product)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrProductNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, u.generateDuplicateErrorP(err)
+		}
+		u.log.Error("Error updating product", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	return product, nil
+}
+
+func (u *ProductRepoM) DeleteByGuid(guid string) error {
+	const op = "ProductRepoM.DeleteByGuid"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.DeleteOne(context.TODO(), bson.M{"guid": guid})
+	if err != nil {
+		u.log.Error("Error deleting product", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrProductNotFound
+	}
+
+	return nil
+}
+
 func (u *ProductRepoM) GetProductsByCategoryGuid(categoryGuid string) ([]*models.Product, error) {
 	const op = "ProductRepoM.GetProductsByCategoryGuid"
 	collection := u.mongo.GetCollection(u.collection)
@@ -100,22 +401,79 @@ func (u *ProductRepoM) GetProductsByCategoryGuid(categoryGuid string) ([]*models
 	return products, nil
 }
 
-func (u *ProductRepoM) generateDuplicateErrorP(err mongo.WriteException) error {
-	const op = "ProductRepoM.generateDuplicateErrorP"
+func (u *ProductRepoM) CountByCategoryGuid(categoryGuid string) (int64, error) {
+	const op = "ProductRepoM.CountByCategoryGuid"
+
+	collection := u.mongo.GetCollection(u.collection)
+	count, err := collection.CountDocuments(context.TODO(), bson.M{"category_id": categoryGuid})
+	if err != nil {
+		u.log.Error("Error counting products", zap.String("op", op), zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MoveToCategory moves every product of a category to another one and
+// returns how many were moved.
+func (u *ProductRepoM) MoveToCategory(categoryGuid string, targetGuid string) (int64, error) {
+	const op = "ProductRepoM.MoveToCategory"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.UpdateMany(
+		context.TODO(),
+		bson.M{"category_id": categoryGuid},
+		bson.M{"$set": bson.M{"category_id": targetGuid}},
+	)
+	if err != nil {
+		u.log.Error("Error moving products", zap.String("op", op), zap.Error(err))
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+func (u *ProductRepoM) DeleteByCategoryGuid(categoryGuid string) (int64, error) {
+	const op = "ProductRepoM.DeleteByCategoryGuid"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.DeleteMany(context.TODO(), bson.M{"category_id": categoryGuid})
+	if err != nil {
+		u.log.Error("Error deleting products", zap.String("op", op), zap.Error(err))
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
 
-	for _, we := range err.WriteErrors {
-		if we.Code == 11000 {
-			u.log.Error("Product with duplicate name", zap.String("op", op), zap.Error(err))
+// generateDuplicateErrorP turns a duplicate key error, from an insert or an
+// update, into DuplicateProductNameError naming the duplicated value.
+func (u *ProductRepoM) generateDuplicateErrorP(err error) error {
+	const op = "ProductRepoM.generateDuplicateErrorP"
 
-			re := regexp.MustCompile(`"(.+?)"`)
-			matches := re.FindStringSubmatch(we.Message)
+	u.log.Error("Product with duplicate name", zap.String("op", op), zap.Error(err))
 
-			if len(matches) > 1 {
-				errorMsg := fmt.Sprintf("%s: Duplicate key violation for index: %s", DuplicateProductNameError, matches[1])
-				return errors.New(errorMsg)
+	var messages []string
+	var writeException mongo.WriteException
+	var commandError mongo.CommandError
+	switch {
+	case errors.As(err, &writeException):
+		for _, we := range writeException.WriteErrors {
+			if we.Code == 11000 {
+				messages = append(messages, we.Message)
 			}
 		}
+	case errors.As(err, &commandError):
+		messages = append(messages, commandError.Message)
 	}
 
-	return nil
+	re := regexp.MustCompile(`"(.+?)"`)
+	for _, message := range messages {
+		matches := re.FindStringSubmatch(message)
+		if len(matches) > 1 {
+			return fmt.Errorf("%w: Duplicate key violation for index: %s", DuplicateProductNameError, matches[1])
+		}
+	}
+
+	return DuplicateProductNameError
 }
@@ -0,0 +1,109 @@
+package mongoRepo
+
+import (
+	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/storage/mongodb"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"time"
+)
+
+type revokedToken struct {
+	Key       string     `bson:"key"`
+	ExpiresAt *time.Time `bson:"expires_at"`
+	CreatedAt *time.Time `bson:"created_at"`
+}
+
+// RevokedTokenRepoM is the access token denylist. A key is either a token
+// id (jti) or a session id (sid); entries are dropped by MongoDB once no
+// token they could match is still valid.
+type RevokedTokenRepoM struct {
+	log        *logging.Logger
+	mongo      *mongodb.MongoDB
+	collection string
+}
+
+func NewRevokedTokenRepoM(log *logging.Logger, mongo *mongodb.MongoDB, collection string) *RevokedTokenRepoM {
+	return &RevokedTokenRepoM{
+		log:        log,
+		mongo:      mongo,
+		collection: collection,
+	}
+}
+
+func (u *RevokedTokenRepoM) CreateIndexesRevokedToken() error {
+	const op = "RevokedTokenRepoM.CreateIndexesRevokedToken"
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"key": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := u.mongo.GetCollection(u.collection).Indexes().CreateMany(context.TODO(), indexModels)
+	if err != nil {
+		u.log.Error("Error creating indexes", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	u.log.Debug("Indexes revoked token created", zap.String("op", op))
+
+	return nil
+}
+
+func (u *RevokedTokenRepoM) Add(keys []string, expiresAt *time.Time) error {
+	const op = "RevokedTokenRepoM.Add"
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	timeNow := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(keys))
+	for _, key := range keys {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"key": key}).
+			SetUpdate(bson.M{
+				"$set":         bson.M{"expires_at": expiresAt},
+				"$setOnInsert": bson.M{"created_at": &timeNow},
+			}).
+			SetUpsert(true),
+		)
+	}
+
+	collection := u.mongo.GetCollection(u.collection)
+	_, err := collection.BulkWrite(context.TODO(), writes)
+	if err != nil {
+		u.log.Error("Error adding revoked tokens", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (u *RevokedTokenRepoM) IsRevoked(keys ...string) (bool, error) {
+	const op = "RevokedTokenRepoM.IsRevoked"
+
+	var token revokedToken
+
+	collection := u.mongo.GetCollection(u.collection)
+	err := collection.FindOne(context.TODO(), bson.M{"key": bson.M{"$in": keys}}).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		u.log.Error("Error checking revoked token", zap.String("op", op), zap.Error(err))
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,218 @@
+package mongoRepo
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/storage/mongodb"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepoM struct {
+	log        *logging.Logger
+	mongo      *mongodb.MongoDB
+	collection string
+}
+
+func NewSessionRepoM(log *logging.Logger, mongo *mongodb.MongoDB, collection string) *SessionRepoM {
+	return &SessionRepoM{
+		log:        log,
+		mongo:      mongo,
+		collection: collection,
+	}
+}
+
+func (u *SessionRepoM) CreateIndexesSession() error {
+	const op = "SessionRepoM.CreateIndexesSession"
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"guid": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.M{"user_id": 1},
+		},
+		{
+			// Expired sessions are removed by MongoDB itself.
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := u.mongo.GetCollection(u.collection).Indexes().CreateMany(context.TODO(), indexModels)
+	if err != nil {
+		u.log.Error("Error creating indexes", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	u.log.Debug("Indexes session created", zap.String("op", op))
+
+	return nil
+}
+
+func (u *SessionRepoM) AddSession(session *models.Session) error {
+	const op = "SessionRepoM.AddSession"
+
+	collection := u.mongo.GetCollection(u.collection)
+	_, err := collection.InsertOne(context.TODO(), session)
+	if err != nil {
+		u.log.Error("Error adding session", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (u *SessionRepoM) GetByGuid(guid string) (*models.Session, error) {
+	const op = "SessionRepoM.GetByGuid"
+	var session *models.Session
+
+	collection := u.mongo.GetCollection(u.collection)
+	err := collection.FindOne(
+		context.TODO(),
+		bson.M{"guid": guid, "expires_at": bson.M{"$gt": time.Now()}},
+	).Decode(&session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrSessionNotFound
+		}
+		u.log.Error("Error getting session by guid", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	return session, nil
+}
+
+func (u *SessionRepoM) GetByUserGuid(userGuid string) ([]*models.Session, error) {
+	const op = "SessionRepoM.GetByUserGuid"
+	collection := u.mongo.GetCollection(u.collection)
+
+	filter := bson.M{
+		"user_id":    userGuid,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.M{"last_used_at": -1})
+
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		u.log.Error("Error getting sessions", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var sessions []*models.Session
+	for cursor.Next(context.TODO()) {
+		var session models.Session
+
+		err = cursor.Decode(&session)
+		if err != nil {
+			u.log.Error("Error decoding session", zap.String("op", op), zap.Error(err))
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = cursor.Err(); err != nil {
+		u.log.Error("Error getting sessions", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RotateRefreshToken replaces the stored refresh token hash only if it still
+// equals oldHash. It reports false when the token was already rotated.
+func (u *SessionRepoM) RotateRefreshToken(
+	guid string,
+	oldHash string,
+	newHash string,
+	expiresAt *time.Time,
+	timeNow *time.Time,
+) (bool, error) {
+	const op = "SessionRepoM.RotateRefreshToken"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.UpdateOne(
+		context.TODO(),
+		bson.M{"guid": guid, "refresh_token_hash": oldHash},
+		bson.M{
+			"$set": bson.M{
+				"refresh_token_hash": newHash,
+				"expires_at":         expiresAt,
+				"last_used_at":       timeNow,
+			},
+		},
+	)
+	if err != nil {
+		u.log.Error("Error rotating session refresh token", zap.String("op", op), zap.Error(err))
+		return false, err
+	}
+
+	return res.MatchedCount == 1, nil
+}
+
+func (u *SessionRepoM) DeleteByGuid(userGuid string, guid string) error {
+	const op = "SessionRepoM.DeleteByGuid"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.DeleteOne(context.TODO(), bson.M{"guid": guid, "user_id": userGuid})
+	if err != nil {
+		u.log.Error("Error deleting session by guid", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// DeleteByUserGuid removes every session of the user except the one with
+// guid exceptGuid, which may be empty to remove all of them. It returns the
+// guids of the removed sessions.
+func (u *SessionRepoM) DeleteByUserGuid(userGuid string, exceptGuid string) ([]string, error) {
+	const op = "SessionRepoM.DeleteByUserGuid"
+
+	filter := bson.M{"user_id": userGuid}
+	if exceptGuid != "" {
+		filter["guid"] = bson.M{"$ne": exceptGuid}
+	}
+
+	collection := u.mongo.GetCollection(u.collection)
+	opts := options.Find().SetProjection(bson.M{"guid": 1})
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		u.log.Error("Error getting sessions", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	var sessions []*models.Session
+	err = cursor.All(context.TODO(), &sessions)
+	if err != nil {
+		u.log.Error("Error decoding sessions", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	guids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		guids = append(guids, session.GUID)
+	}
+	if len(guids) == 0 {
+		return guids, nil
+	}
+
+	_, err = collection.DeleteMany(context.TODO(), bson.M{"guid": bson.M{"$in": guids}})
+	if err != nil {
+		u.log.Error("Error deleting sessions by user guid", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return guids, nil
+}
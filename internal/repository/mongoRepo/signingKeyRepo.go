@@ -0,0 +1,129 @@
+package mongoRepo
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/storage/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"time"
+)
+
+type SigningKeyRepoM struct {
+	log        *logging.Logger
+	mongo      *mongodb.MongoDB
+	collection string
+}
+
+func NewSigningKeyRepoM(log *logging.Logger, mongo *mongodb.MongoDB, collection string) *SigningKeyRepoM {
+	return &SigningKeyRepoM{
+		log:        log,
+		mongo:      mongo,
+		collection: collection,
+	}
+}
+
+func (u *SigningKeyRepoM) CreateIndexesSigningKey() error {
+	const op = "SigningKeyRepoM.CreateIndexesSigningKey"
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"kid": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Retired keys are removed once no token signed with them is valid.
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := u.mongo.GetCollection(u.collection).Indexes().CreateMany(context.TODO(), indexModels)
+	if err != nil {
+		u.log.Error("Error creating indexes", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	u.log.Debug("Indexes signing key created", zap.String("op", op))
+
+	return nil
+}
+
+// GetKeys returns the keys of the algorithm that can still verify tokens,
+// newest first.
+func (u *SigningKeyRepoM) GetKeys(algorithm string) ([]*models.SigningKey, error) {
+	const op = "SigningKeyRepoM.GetKeys"
+	collection := u.mongo.GetCollection(u.collection)
+
+	filter := bson.M{
+		"algorithm": algorithm,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		u.log.Error("Error getting signing keys", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	var keys []*models.SigningKey
+	err = cursor.All(context.TODO(), &keys)
+	if err != nil {
+		u.log.Error("Error decoding signing keys", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (u *SigningKeyRepoM) AddKey(key *models.SigningKey) error {
+	const op = "SigningKeyRepoM.AddKey"
+
+	collection := u.mongo.GetCollection(u.collection)
+	_, err := collection.InsertOne(context.TODO(), key)
+	if err != nil {
+		u.log.Error("Error adding signing key", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RetireKeys marks every key of the algorithm created before createdBefore
+// as retired. Retired keys only verify tokens until expiresAt.
+func (u *SigningKeyRepoM) RetireKeys(
+	algorithm string,
+	createdBefore *time.Time,
+	timeNow *time.Time,
+	expiresAt *time.Time,
+) error {
+	const op = "SigningKeyRepoM.RetireKeys"
+
+	collection := u.mongo.GetCollection(u.collection)
+	_, err := collection.UpdateMany(
+		context.TODO(),
+		bson.M{
+			"algorithm":  algorithm,
+			"created_at": bson.M{"$lt": createdBefore},
+			"retired_at": bson.M{"$exists": false},
+		},
+		bson.M{
+			"$set": bson.M{
+				"retired_at": timeNow,
+				"expires_at": expiresAt,
+			},
+		},
+	)
+	if err != nil {
+		u.log.Error("Error retiring signing keys", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -7,12 +7,23 @@ import (
 	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
 	"time"
 )
 
 var ErrUserNotFound = errors.New("user not found")
+var ErrUserLoginTaken = errors.New("login already taken")
+
+// loginCollation compares logins ignoring case. The unique login index and
+// every query by login use it, so that the index is used.
+var loginCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// LoginConflict is a group of users whose logins only differ in case.
+type LoginConflict struct {
+	Users []*models.User `bson:"users" json:"users"`
+}
 
 type UserRepoM struct {
 	log        *logging.Logger
@@ -28,8 +39,71 @@ func NewUserRepoM(log *logging.Logger, mongo *mongodb.MongoDB, collection string
 	}
 }
 
-func (u *UserRepoM) UpdateUnLoggingUser(guid string, timeNow *time.Time) error {
-	const op = "UserRepoM.UpdateUnLoggingUser"
+// CreateIndexesUser creates the unique guid index and the unique
+// case-insensitive login index. The login index cannot be created while
+// logins conflict; GetLoginConflicts lists them.
+func (u *UserRepoM) CreateIndexesUser() error {
+	const op = "UserRepoM.CreateIndexesUser"
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"guid": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"login": 1},
+			Options: options.Index().SetUnique(true).SetCollation(loginCollation),
+		},
+	}
+
+	for _, indexModel := range indexModels {
+		_, err := u.mongo.GetCollection(u.collection).Indexes().CreateOne(context.TODO(), indexModel)
+		if err != nil {
+			u.log.Error("Error creating indexes", zap.String("op", op), zap.Error(err))
+			return err
+		}
+	}
+
+	u.log.Debug("Indexes user created", zap.String("op", op))
+
+	return nil
+}
+
+// GetLoginConflicts returns the groups of users whose logins are equal
+// ignoring case.
+func (u *UserRepoM) GetLoginConflicts() ([]*LoginConflict, error) {
+	const op = "UserRepoM.GetLoginConflicts"
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$login",
+			"users": bson.M{"$push": bson.M{"guid": "$guid", "login": "$login", "created_at": "$created_at"}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	collection := u.mongo.GetCollection(u.collection)
+	cursor, err := collection.Aggregate(context.TODO(), pipeline, options.Aggregate().SetCollation(loginCollation))
+	if err != nil {
+		u.log.Error("Error getting login conflicts", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	conflicts := []*LoginConflict{}
+	err = cursor.All(context.TODO(), &conflicts)
+	if err != nil {
+		u.log.Error("Error decoding login conflicts", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+func (u *UserRepoM) UpdatedLoggingUser(guid string, timeNow *time.Time) error {
+	const op = "UserRepoM.UpdatedLoggingUser"
 
 	collection := u.mongo.GetCollection(u.collection)
 	_, err := collection.UpdateOne(
@@ -37,67 +111,247 @@ func (u *UserRepoM) UpdateUnLoggingUser(guid string, timeNow *time.Time) error {
 		bson.M{"guid": guid},
 		bson.M{
 			"$set": bson.M{
-				"updated_at": timeNow,
-				"is_logged":  false,
+				"last_login_at": timeNow,
+				"updated_at":    timeNow,
 			},
 		},
 	)
+
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return ErrUserNotFound
 		}
-		u.log.Error("Error updating user refresh token", zap.String("op", op), zap.Error(err))
+		u.log.Error("Error updating user last login", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (u *UserRepoM) UpdateRole(guid string, role string, timeNow *time.Time) error {
+	const op = "UserRepoM.UpdateRole"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.UpdateOne(
+		context.TODO(),
+		bson.M{"guid": guid},
+		bson.M{
+			"$set": bson.M{
+				"role":       role,
+				"updated_at": timeNow,
+			},
+		},
+	)
+	if err != nil {
+		u.log.Error("Error updating user role", zap.String("op", op), zap.Error(err))
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
 
 	return nil
 }
 
-func (u *UserRepoM) UpdatedLoggingUser(guid string, rt string, timeNow *time.Time) error {
-	const op = "UserRepoM.UpdatedLoggingUser"
+func (u *UserRepoM) UpdateEmailVerified(guid string, timeNow *time.Time) error {
+	const op = "UserRepoM.UpdateEmailVerified"
 
 	collection := u.mongo.GetCollection(u.collection)
-	_, err := collection.UpdateOne(
+	res, err := collection.UpdateOne(
 		context.TODO(),
 		bson.M{"guid": guid},
 		bson.M{
 			"$set": bson.M{
-				"refresh_token": rt,
-				"last_login_at": timeNow,
-				"updated_at":    timeNow,
-				"is_logged":     true,
+				"email_verified_at": timeNow,
+				"updated_at":        timeNow,
 			},
 		},
 	)
+	if err != nil {
+		u.log.Error("Error updating user email verification", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateProfile sets the given fields of the user, leaving nil ones as they
+// are, and returns the updated user.
+func (u *UserRepoM) UpdateProfile(guid string, name *string, lastName *string, timeNow *time.Time) (*models.User, error) {
+	const op = "UserRepoM.UpdateProfile"
+
+	set := bson.M{"updated_at": timeNow}
+	if name != nil {
+		set["name"] = *name
+	}
+	if lastName != nil {
+		set["last_name"] = *lastName
+	}
+
+	var user *models.User
 
+	collection := u.mongo.GetCollection(u.collection)
+	err := collection.FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"guid": guid},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrUserNotFound
+			return nil, ErrUserNotFound
 		}
-		u.log.Error("Error updating user refresh token", zap.String("op", op), zap.Error(err))
+		u.log.Error("Error updating user profile", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u *UserRepoM) TouchUpdatedAt(guid string, timeNow *time.Time) error {
+	const op = "UserRepoM.TouchUpdatedAt"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.UpdateOne(
+		context.TODO(),
+		bson.M{"guid": guid},
+		bson.M{"$set": bson.M{"updated_at": timeNow}},
+	)
+	if err != nil {
+		u.log.Error("Error updating user", zap.String("op", op), zap.Error(err))
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
 	return nil
 }
 
+// ScheduleDeletion marks the user to be deleted at the given time. Passing
+// nil cancels a scheduled deletion.
+func (u *UserRepoM) ScheduleDeletion(guid string, deleteAt *time.Time, timeNow *time.Time) error {
+	const op = "UserRepoM.ScheduleDeletion"
+
+	update := bson.M{
+		"$set": bson.M{
+			"deletion_scheduled_at": deleteAt,
+			"updated_at":            timeNow,
+		},
+	}
+	if deleteAt == nil {
+		update = bson.M{
+			"$set":   bson.M{"updated_at": timeNow},
+			"$unset": bson.M{"deletion_scheduled_at": ""},
+		}
+	}
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.UpdateOne(context.TODO(), bson.M{"guid": guid}, update)
+	if err != nil {
+		u.log.Error("Error scheduling user deletion", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetDeletionDue returns the users whose scheduled deletion time has come.
+func (u *UserRepoM) GetDeletionDue(timeNow *time.Time) ([]*models.User, error) {
+	const op = "UserRepoM.GetDeletionDue"
+
+	collection := u.mongo.GetCollection(u.collection)
+	cursor, err := collection.Find(context.TODO(), bson.M{"deletion_scheduled_at": bson.M{"$lte": timeNow}})
+	if err != nil {
+		u.log.Error("Error getting users due for deletion", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var users []*models.User
+	err = cursor.All(context.TODO(), &users)
+	if err != nil {
+		u.log.Error("Error decoding users", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (u *UserRepoM) CountByRole(role string) (int64, error) {
+	const op = "UserRepoM.CountByRole"
+
+	collection := u.mongo.GetCollection(u.collection)
+	count, err := collection.CountDocuments(context.TODO(), bson.M{"role": role})
+	if err != nil {
+		u.log.Error("Error counting users by role", zap.String("op", op), zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (u *UserRepoM) AddUser(user *models.User) error {
 	const op = "UserRepoM.AddUser"
 
 	collection := u.mongo.GetCollection(u.collection)
 	_, err := collection.InsertOne(context.TODO(), user)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrUserLoginTaken
+		}
 		u.log.Error("Error adding user", zap.String("op", op), zap.Error(err))
 		return err
 	}
 	return nil
 }
 
+// AddUserIfMissing inserts the user unless a user with the same guid exists,
+// so it can be repeated safely. It reports whether the user was inserted.
+// ErrUserLoginTaken is returned if another user has the login.
+func (u *UserRepoM) AddUserIfMissing(user *models.User) (bool, error) {
+	const op = "UserRepoM.AddUserIfMissing"
+
+	collection := u.mongo.GetCollection(u.collection)
+	res, err := collection.UpdateOne(
+		context.TODO(),
+		bson.M{"guid": user.GUID},
+		bson.M{"$setOnInsert": user},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// A concurrent upsert of the same user violates the guid index.
+			_, err2 := u.GetByGuid(user.GUID)
+			if err2 == nil {
+				return false, nil
+			}
+			return false, ErrUserLoginTaken
+		}
+		u.log.Error("Error adding user", zap.String("op", op), zap.Error(err))
+		return false, err
+	}
+
+	return res.UpsertedCount > 0, nil
+}
+
+// GetByLogin finds the user by login ignoring case. The login is normalized
+// first, so it may be passed as the user typed it.
 func (u *UserRepoM) GetByLogin(login string) (*models.User, error) {
 	const op = "UserRepoM.GetByLogin"
 	var user *models.User
 
 	collection := u.mongo.GetCollection(u.collection)
-	err := collection.FindOne(context.TODO(), bson.M{"login": login}).Decode(&user)
+	err := collection.FindOne(
+		context.TODO(),
+		bson.M{"login": models.NormalizeLogin(login)},
+		options.FindOne().SetCollation(loginCollation),
+	).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, ErrUserNotFound
@@ -0,0 +1,150 @@
+package postgresRepo
+
+import (
+	"PetProjectGo/pkg/logging"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"time"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey lets a user or a service call the API without a session. The key
+// is its prefix followed by a secret; only the hash of the secret is stored.
+type APIKey struct {
+	GUID       string         `db:"guid" json:"id"`
+	UserGUID   string         `db:"user_guid" json:"user_id"`
+	Name       string         `db:"name" json:"name"`
+	Prefix     string         `db:"prefix" json:"prefix"`
+	SecretHash string         `db:"secret_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  *time.Time     `db:"created_at" json:"created_at"`
+}
+
+type APIKeyRepoP struct {
+	log      *logging.Logger
+	postgres *sqlx.DB
+}
+
+func NewAPIKeyRepoP(log *logging.Logger, postgres *sqlx.DB) *APIKeyRepoP {
+	return &APIKeyRepoP{
+		log:      log,
+		postgres: postgres,
+	}
+}
+
+func (a *APIKeyRepoP) AddKey(key *APIKey) error {
+	const op = "APIKeyRepoP.AddKey"
+
+	query := `INSERT INTO api_keys (guid, user_guid, name, prefix, secret_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := a.postgres.Exec(
+		query, key.GUID, key.UserGUID, key.Name, key.Prefix, key.SecretHash, key.Scopes, key.ExpiresAt, key.CreatedAt,
+	)
+	if err != nil {
+		a.log.Error("Error adding api key", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (a *APIKeyRepoP) GetByPrefix(prefix string) (*APIKey, error) {
+	const op = "APIKeyRepoP.GetByPrefix"
+
+	var key APIKey
+
+	query := `SELECT guid, user_guid, name, prefix, secret_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys WHERE prefix = $1`
+
+	err := a.postgres.Get(&key, query, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		a.log.Error("Error getting api key", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetByUser returns the keys of the user that are not revoked, newest first.
+func (a *APIKeyRepoP) GetByUser(userGuid string) ([]*APIKey, error) {
+	const op = "APIKeyRepoP.GetByUser"
+
+	keys := []*APIKey{}
+
+	query := `SELECT guid, user_guid, name, prefix, secret_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys WHERE user_guid = $1 AND revoked_at IS NULL ORDER BY created_at DESC`
+
+	err := a.postgres.Select(&keys, query, userGuid)
+	if err != nil {
+		a.log.Error("Error getting api keys", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// CountActive counts the keys of the user that are neither revoked nor expired.
+func (a *APIKeyRepoP) CountActive(userGuid string, timeNow *time.Time) (int, error) {
+	const op = "APIKeyRepoP.CountActive"
+
+	var count int
+
+	query := `SELECT COUNT(*) FROM api_keys WHERE user_guid = $1 AND revoked_at IS NULL AND expires_at > $2`
+
+	err := a.postgres.Get(&count, query, userGuid, timeNow)
+	if err != nil {
+		a.log.Error("Error counting api keys", zap.String("op", op), zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (a *APIKeyRepoP) Revoke(userGuid string, guid string, timeNow *time.Time) error {
+	const op = "APIKeyRepoP.Revoke"
+
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE guid = $2 AND user_guid = $3 AND revoked_at IS NULL`
+
+	res, err := a.postgres.Exec(query, timeNow, guid, userGuid)
+	if err != nil {
+		a.log.Error("Error revoking api key", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed records the use of the key. It writes at most once per
+// interval, so a busy key does not cause a write on every request.
+func (a *APIKeyRepoP) TouchLastUsed(guid string, timeNow *time.Time, interval time.Duration) error {
+	const op = "APIKeyRepoP.TouchLastUsed"
+
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE guid = $2 AND (last_used_at IS NULL OR last_used_at < $3)`
+
+	_, err := a.postgres.Exec(query, timeNow, guid, timeNow.Add(-interval))
+	if err != nil {
+		a.log.Error("Error updating api key last use", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
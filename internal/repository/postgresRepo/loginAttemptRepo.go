@@ -0,0 +1,125 @@
+package postgresRepo
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/pkg/logging"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	LoginAttemptKeyLogin = "login"
+	LoginAttemptKeyIP    = "ip"
+)
+
+var ErrLoginAttemptNotFound = errors.New("login attempt not found")
+
+// LoginAttempt counts the recent failed logins for a login or a client IP.
+type LoginAttempt struct {
+	KeyType      string     `db:"key_type"`
+	Key          string     `db:"key"`
+	FailedCount  int        `db:"failed_count"`
+	LastFailedAt *time.Time `db:"last_failed_at"`
+	LockedUntil  *time.Time `db:"locked_until"`
+}
+
+type LoginAttemptRepoP struct {
+	log      *logging.Logger
+	postgres *sqlx.DB
+}
+
+func NewLoginAttemptRepoP(log *logging.Logger, postgres *sqlx.DB) *LoginAttemptRepoP {
+	return &LoginAttemptRepoP{
+		log:      log,
+		postgres: postgres,
+	}
+}
+
+func (l *LoginAttemptRepoP) Get(keyType string, key string) (*LoginAttempt, error) {
+	const op = "LoginAttemptRepoP.Get"
+
+	var attempt LoginAttempt
+
+	query := `SELECT key_type, key, failed_count, last_failed_at, locked_until
+		FROM login_attempts WHERE key_type = $1 AND key = $2`
+
+	err := l.postgres.Get(&attempt, query, keyType, attemptKey(keyType, key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLoginAttemptNotFound
+		}
+		l.log.Error("Error getting login attempt", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// AddFailure counts a failed login and returns the updated counter. Failures
+// that happened before windowStart are forgotten.
+func (l *LoginAttemptRepoP) AddFailure(
+	keyType string,
+	key string,
+	timeNow *time.Time,
+	windowStart *time.Time,
+) (*LoginAttempt, error) {
+	const op = "LoginAttemptRepoP.AddFailure"
+
+	var attempt LoginAttempt
+
+	query := `INSERT INTO login_attempts (key_type, key, failed_count, last_failed_at) VALUES ($1, $2, 1, $3)
+		ON CONFLICT (key_type, key) DO UPDATE SET
+			failed_count = CASE WHEN login_attempts.last_failed_at < $4 THEN 1 ELSE login_attempts.failed_count + 1 END,
+			last_failed_at = $3
+		RETURNING key_type, key, failed_count, last_failed_at, locked_until`
+
+	err := l.postgres.Get(&attempt, query, keyType, attemptKey(keyType, key), timeNow, windowStart)
+	if err != nil {
+		l.log.Error("Error adding login failure", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// Lock refuses logins for the key until the given time and starts counting
+// failures from zero again.
+func (l *LoginAttemptRepoP) Lock(keyType string, key string, lockedUntil *time.Time) error {
+	const op = "LoginAttemptRepoP.Lock"
+
+	query := `UPDATE login_attempts SET locked_until = $3, failed_count = 0 WHERE key_type = $1 AND key = $2`
+
+	_, err := l.postgres.Exec(query, keyType, attemptKey(keyType, key), lockedUntil)
+	if err != nil {
+		l.log.Error("Error locking login", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Reset forgets the failures and the lock of the key.
+func (l *LoginAttemptRepoP) Reset(keyType string, key string) error {
+	const op = "LoginAttemptRepoP.Reset"
+
+	query := `DELETE FROM login_attempts WHERE key_type = $1 AND key = $2`
+
+	_, err := l.postgres.Exec(query, keyType, attemptKey(keyType, key))
+	if err != nil {
+		l.log.Error("Error resetting login attempts", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// attemptKey counts the failures of a login however its case is typed.
+func attemptKey(keyType string, key string) string {
+	if keyType == LoginAttemptKeyLogin {
+		return models.LoginKey(key)
+	}
+	return key
+}
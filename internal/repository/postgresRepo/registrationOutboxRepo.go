@@ -0,0 +1,123 @@
+package postgresRepo
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/pkg/logging"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+var ErrRegistrationNotFound = errors.New("registration not found")
+
+// Registration is an outbox entry written together with the credentials of a
+// new user. Payload is the Mongo user as JSON; the entry is processed once
+// the user is in Mongo too.
+type Registration struct {
+	UserGUID    string     `db:"user_guid"`
+	Login       string     `db:"login"`
+	Payload     []byte     `db:"payload"`
+	Attempts    int        `db:"attempts"`
+	LastError   *string    `db:"last_error"`
+	ProcessedAt *time.Time `db:"processed_at"`
+	CreatedAt   *time.Time `db:"created_at"`
+}
+
+type RegistrationOutboxRepoP struct {
+	log      *logging.Logger
+	postgres *sqlx.DB
+}
+
+func NewRegistrationOutboxRepoP(log *logging.Logger, postgres *sqlx.DB) *RegistrationOutboxRepoP {
+	return &RegistrationOutboxRepoP{
+		log:      log,
+		postgres: postgres,
+	}
+}
+
+func (r *RegistrationOutboxRepoP) Get(userGuid string) (*Registration, error) {
+	const op = "RegistrationOutboxRepoP.Get"
+
+	var registration Registration
+
+	query := `SELECT user_guid, login, payload, attempts, last_error, processed_at, created_at
+		FROM registration_outbox WHERE user_guid = $1`
+
+	err := r.postgres.Get(&registration, query, userGuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRegistrationNotFound
+		}
+		r.log.Error("Error getting registration", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return &registration, nil
+}
+
+// GetPending returns up to limit unprocessed entries, oldest first.
+func (r *RegistrationOutboxRepoP) GetPending(limit int) ([]*Registration, error) {
+	const op = "RegistrationOutboxRepoP.GetPending"
+
+	var registrations []*Registration
+
+	query := `SELECT user_guid, login, payload, attempts, last_error, processed_at, created_at
+		FROM registration_outbox WHERE processed_at IS NULL ORDER BY created_at LIMIT $1`
+
+	err := r.postgres.Select(&registrations, query, limit)
+	if err != nil {
+		r.log.Error("Error getting pending registrations", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return registrations, nil
+}
+
+// HasPendingLogin reports whether a registration with the login, ignoring
+// case, is not in Mongo yet.
+func (r *RegistrationOutboxRepoP) HasPendingLogin(login string) (bool, error) {
+	const op = "RegistrationOutboxRepoP.HasPendingLogin"
+
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM registration_outbox WHERE lower(login) = $1 AND processed_at IS NULL)`
+
+	err := r.postgres.Get(&exists, query, models.LoginKey(login))
+	if err != nil {
+		r.log.Error("Error checking pending registration", zap.String("op", op), zap.Error(err))
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *RegistrationOutboxRepoP) MarkProcessed(userGuid string, timeNow *time.Time) error {
+	const op = "RegistrationOutboxRepoP.MarkProcessed"
+
+	query := `UPDATE registration_outbox SET processed_at = $1, attempts = attempts + 1, last_error = NULL
+		WHERE user_guid = $2`
+
+	_, err := r.postgres.Exec(query, timeNow, userGuid)
+	if err != nil {
+		r.log.Error("Error marking registration processed", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *RegistrationOutboxRepoP) MarkFailed(userGuid string, lastError string) error {
+	const op = "RegistrationOutboxRepoP.MarkFailed"
+
+	query := `UPDATE registration_outbox SET attempts = attempts + 1, last_error = $1 WHERE user_guid = $2`
+
+	_, err := r.postgres.Exec(query, lastError, userGuid)
+	if err != nil {
+		r.log.Error("Error marking registration failed", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package postgresRepo
+
+import (
+	"PetProjectGo/pkg/logging"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+var ErrTOTPNotFound = errors.New("totp not found")
+var ErrTOTPAlreadyEnabled = errors.New("totp already enabled")
+
+// TOTP is the two-factor secret of a user. It is in effect only once
+// EnabledAt is set, i.e. after the user confirmed a code from the app.
+type TOTP struct {
+	UserGUID     string     `db:"user_guid"`
+	Secret       string     `db:"secret"`
+	LastUsedStep *int64     `db:"last_used_step"`
+	EnabledAt    *time.Time `db:"enabled_at"`
+	CreatedAt    *time.Time `db:"created_at"`
+}
+
+type RecoveryCode struct {
+	GUID      string     `db:"guid"`
+	UserGUID  string     `db:"user_guid"`
+	CodeHash  string     `db:"code_hash"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt *time.Time `db:"created_at"`
+}
+
+type TwoFactorRepoP struct {
+	log      *logging.Logger
+	postgres *sqlx.DB
+}
+
+func NewTwoFactorRepoP(log *logging.Logger, postgres *sqlx.DB) *TwoFactorRepoP {
+	return &TwoFactorRepoP{
+		log:      log,
+		postgres: postgres,
+	}
+}
+
+func (t *TwoFactorRepoP) GetTOTP(userGuid string) (*TOTP, error) {
+	const op = "TwoFactorRepoP.GetTOTP"
+
+	var totp TOTP
+
+	query := `SELECT user_guid, secret, last_used_step, enabled_at, created_at FROM user_totp WHERE user_guid = $1`
+
+	err := t.postgres.Get(&totp, query, userGuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTOTPNotFound
+		}
+		t.log.Error("Error getting totp", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+// SaveTOTP stores a new pending secret, replacing an unconfirmed one. An
+// enabled secret is never replaced.
+func (t *TwoFactorRepoP) SaveTOTP(userGuid string, secret string, timeNow *time.Time) error {
+	const op = "TwoFactorRepoP.SaveTOTP"
+
+	query := `INSERT INTO user_totp (user_guid, secret, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_guid) DO UPDATE SET secret = $2, last_used_step = NULL, created_at = $3
+		WHERE user_totp.enabled_at IS NULL`
+
+	res, err := t.postgres.Exec(query, userGuid, secret, timeNow)
+	if err != nil {
+		t.log.Error("Error saving totp", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// EnableTOTP turns the pending secret on and replaces the recovery codes of
+// the user in one transaction.
+func (t *TwoFactorRepoP) EnableTOTP(userGuid string, codes []*RecoveryCode, timeNow *time.Time) error {
+	const op = "TwoFactorRepoP.EnableTOTP"
+
+	tx, err := t.postgres.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE user_totp SET enabled_at = $1 WHERE user_guid = $2 AND enabled_at IS NULL`,
+		timeNow, userGuid,
+	)
+	if err != nil {
+		t.log.Error("Error enabling totp", zap.String("op", op), zap.Error(err))
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTOTPNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_guid = $1`, userGuid)
+	if err != nil {
+		t.log.Error("Error deleting recovery codes", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	for _, code := range codes {
+		_, err = tx.Exec(
+			`INSERT INTO recovery_codes (guid, user_guid, code_hash, created_at) VALUES ($1, $2, $3, $4)`,
+			code.GUID, code.UserGUID, code.CodeHash, code.CreatedAt,
+		)
+		if err != nil {
+			t.log.Error("Error adding recovery code", zap.String("op", op), zap.Error(err))
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records the time step of an accepted code. It returns false if a
+// code of this or a later step was already accepted, so a code works once.
+func (t *TwoFactorRepoP) UseStep(userGuid string, step int64) (bool, error) {
+	const op = "TwoFactorRepoP.UseStep"
+
+	query := `UPDATE user_totp SET last_used_step = $2
+		WHERE user_guid = $1 AND (last_used_step IS NULL OR last_used_step < $2)`
+
+	res, err := t.postgres.Exec(query, userGuid, step)
+	if err != nil {
+		t.log.Error("Error using totp step", zap.String("op", op), zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used. It returns
+// false if the user has no such unused code.
+func (t *TwoFactorRepoP) ConsumeRecoveryCode(userGuid string, codeHash string, timeNow *time.Time) (bool, error) {
+	const op = "TwoFactorRepoP.ConsumeRecoveryCode"
+
+	query := `UPDATE recovery_codes SET used_at = $1 WHERE user_guid = $2 AND code_hash = $3 AND used_at IS NULL`
+
+	res, err := t.postgres.Exec(query, timeNow, userGuid, codeHash)
+	if err != nil {
+		t.log.Error("Error consuming recovery code", zap.String("op", op), zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has.
+func (t *TwoFactorRepoP) CountRecoveryCodes(userGuid string) (int, error) {
+	const op = "TwoFactorRepoP.CountRecoveryCodes"
+
+	var count int
+
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_guid = $1 AND used_at IS NULL`
+
+	err := t.postgres.Get(&count, query, userGuid)
+	if err != nil {
+		t.log.Error("Error counting recovery codes", zap.String("op", op), zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// DeleteTOTP removes the secret and the recovery codes of the user.
+func (t *TwoFactorRepoP) DeleteTOTP(userGuid string) error {
+	const op = "TwoFactorRepoP.DeleteTOTP"
+
+	tx, err := t.postgres.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_guid = $1`, userGuid)
+	if err != nil {
+		t.log.Error("Error deleting recovery codes", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_totp WHERE user_guid = $1`, userGuid)
+	if err != nil {
+		t.log.Error("Error deleting totp", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -2,6 +2,7 @@ package postgresRepo
 
 import (
 	"PetProjectGo/pkg/logging"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 	"time"
@@ -22,29 +23,279 @@ func NewUserRepoP(log *logging.Logger, postgres *sqlx.DB) *UserRepoP {
 type User struct {
 	GUID           string
 	HashedPassword string
+	Algorithm      string
+	MustChange     bool
 	CreatedAt      *time.Time
 }
 
+// Credentials describes the password of a user without the hash itself.
+type Credentials struct {
+	Algorithm  string     `db:"algorithm" json:"algorithm"`
+	MustChange bool       `db:"must_change" json:"must_change"`
+	CreatedAt  *time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at" json:"updated_at"`
+}
+
 func (u *UserRepoP) GetHashPasswordByGuid(guid string) (string, error) {
 	var hashedPassword string
 
-	query := `SELECT hashed_password FROM passwords WHERE guid = $1`
+	query := `SELECT hashed_password FROM credentials WHERE user_guid = $1`
 
 	err := u.postgres.QueryRow(query, guid).Scan(&hashedPassword)
 	return hashedPassword, err
 }
 
+func (u *UserRepoP) GetCredentials(guid string) (*Credentials, error) {
+	const op = "UserRepoP.GetCredentials"
+
+	var credentials Credentials
+
+	query := `SELECT algorithm, must_change, created_at, updated_at FROM credentials WHERE user_guid = $1`
+
+	err := u.postgres.Get(&credentials, query, guid)
+	if err != nil {
+		u.log.Error("Error getting credentials", zap.String("op", op), zap.Error(err))
+
+		return nil, err
+	}
+
+	return &credentials, nil
+}
+
+// GetPasswordChanges returns when the passwords kept in the history were replaced.
+func (u *UserRepoP) GetPasswordChanges(guid string) ([]time.Time, error) {
+	const op = "UserRepoP.GetPasswordChanges"
+
+	changes := []time.Time{}
+
+	query := `SELECT created_at FROM password_history WHERE user_guid = $1 ORDER BY created_at DESC`
+
+	err := u.postgres.Select(&changes, query, guid)
+	if err != nil {
+		u.log.Error("Error getting password changes", zap.String("op", op), zap.Error(err))
+
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+const addUserQuery = `INSERT INTO credentials (user_guid, hashed_password, algorithm, must_change, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $5)`
+
 func (u *UserRepoP) AddUser(nur *User) error {
 	const op = "UserRepoP.AddUser"
 
-	query := `INSERT INTO passwords (guid, hashed_password, created_at) VALUES ($1, $2, $3)`
+	_, err := u.postgres.Exec(addUserQuery, nur.GUID, nur.HashedPassword, nur.Algorithm, nur.MustChange, nur.CreatedAt)
+	if err != nil {
+		u.log.Error("Error adding user", zap.String("op", op), zap.Error(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// AddUserWithOutbox stores the credentials and the outbox entry of a new user
+// in one transaction, so the Mongo part of the registration can always be
+// completed later from the entry.
+func (u *UserRepoP) AddUserWithOutbox(nur *User, registration *Registration) error {
+	const op = "UserRepoP.AddUserWithOutbox"
 
-	_, err := u.postgres.Exec(query, nur.GUID, nur.HashedPassword, nur.CreatedAt)
+	tx, err := u.postgres.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(addUserQuery, nur.GUID, nur.HashedPassword, nur.Algorithm, nur.MustChange, nur.CreatedAt)
 	if err != nil {
 		u.log.Error("Error adding user", zap.String("op", op), zap.Error(err))
 
 		return err
 	}
 
+	_, err = tx.Exec(
+		`INSERT INTO registration_outbox (user_guid, login, payload, created_at) VALUES ($1, $2, $3, $4)`,
+		registration.UserGUID, registration.Login, registration.Payload, registration.CreatedAt,
+	)
+	if err != nil {
+		u.log.Error("Error adding registration", zap.String("op", op), zap.Error(err))
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAllGuids returns the guid of every user with credentials.
+func (u *UserRepoP) GetAllGuids() ([]string, error) {
+	const op = "UserRepoP.GetAllGuids"
+
+	var guids []string
+
+	err := u.postgres.Select(&guids, `SELECT user_guid FROM credentials`)
+	if err != nil {
+		u.log.Error("Error getting user guids", zap.String("op", op), zap.Error(err))
+
+		return nil, err
+	}
+
+	return guids, nil
+}
+
+// HasUser reports whether the user has credentials.
+func (u *UserRepoP) HasUser(guid string) (bool, error) {
+	const op = "UserRepoP.HasUser"
+
+	var exists bool
+
+	err := u.postgres.Get(&exists, `SELECT EXISTS(SELECT 1 FROM credentials WHERE user_guid = $1)`, guid)
+	if err != nil {
+		u.log.Error("Error checking credentials", zap.String("op", op), zap.Error(err))
+
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// GetRecentPasswords returns the current hash of the user followed by the
+// previous ones, newest first, limit hashes at most.
+func (u *UserRepoP) GetRecentPasswords(guid string, limit int) ([]string, error) {
+	const op = "UserRepoP.GetRecentPasswords"
+
+	var hashes []string
+
+	query := `SELECT hashed_password FROM (
+			SELECT hashed_password, updated_at AS changed_at FROM credentials WHERE user_guid = $1
+			UNION ALL
+			SELECT hashed_password, created_at AS changed_at FROM password_history WHERE user_guid = $1
+		) AS passwords ORDER BY changed_at DESC LIMIT $2`
+
+	err := u.postgres.Select(&hashes, query, guid, limit)
+	if err != nil {
+		u.log.Error("Error getting recent passwords", zap.String("op", op), zap.Error(err))
+
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// UpdatePassword sets a new password, moving the current one to the history.
+// Only the historySize newest entries of the history are kept.
+func (u *UserRepoP) UpdatePassword(
+	guid string,
+	hashedPassword string,
+	algorithm string,
+	historySize int,
+	timeNow *time.Time,
+) error {
+	const op = "UserRepoP.UpdatePassword"
+
+	tx, err := u.postgres.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO password_history (guid, user_guid, hashed_password, created_at)
+		SELECT $1, user_guid, hashed_password, $3 FROM credentials WHERE user_guid = $2`,
+		uuid.New().String(), guid, timeNow,
+	)
+	if err != nil {
+		u.log.Error("Error saving password history", zap.String("op", op), zap.Error(err))
+
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE credentials SET hashed_password = $1, algorithm = $2, must_change = FALSE, updated_at = $3
+		WHERE user_guid = $4`,
+		hashedPassword, algorithm, timeNow, guid,
+	)
+	if err != nil {
+		u.log.Error("Error updating password", zap.String("op", op), zap.Error(err))
+
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM password_history WHERE user_guid = $1 AND guid NOT IN (
+			SELECT guid FROM password_history WHERE user_guid = $1 ORDER BY created_at DESC LIMIT $2
+		)`,
+		guid, historySize,
+	)
+	if err != nil {
+		u.log.Error("Error pruning password history", zap.String("op", op), zap.Error(err))
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RehashPassword replaces the hash only if it is still oldHashedPassword, so
+// a password changed in the meantime is not overwritten. The password stays
+// the same, so the history is not touched.
+func (u *UserRepoP) RehashPassword(guid string, oldHashedPassword string, hashedPassword string, algorithm string) error {
+	const op = "UserRepoP.RehashPassword"
+
+	query := `UPDATE credentials SET hashed_password = $1, algorithm = $2
+		WHERE user_guid = $3 AND hashed_password = $4`
+
+	_, err := u.postgres.Exec(query, hashedPassword, algorithm, guid, oldHashedPassword)
+	if err != nil {
+		u.log.Error("Error rehashing password", zap.String("op", op), zap.Error(err))
+
+		return err
+	}
+
 	return nil
 }
+
+// DeleteUser removes everything kept in Postgres about the user in one
+// transaction: credentials, password history, one-time tokens, 2FA secrets,
+// the registration outbox entry, API keys and the failed login counter of
+// the login.
+func (u *UserRepoP) DeleteUser(guid string, login string) error {
+	const op = "UserRepoP.DeleteUser"
+
+	tx, err := u.postgres.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`DELETE FROM credentials WHERE user_guid = $1`,
+		`DELETE FROM password_history WHERE user_guid = $1`,
+		`DELETE FROM user_tokens WHERE user_guid = $1`,
+		`DELETE FROM recovery_codes WHERE user_guid = $1`,
+		`DELETE FROM user_totp WHERE user_guid = $1`,
+		`DELETE FROM registration_outbox WHERE user_guid = $1`,
+		`DELETE FROM api_keys WHERE user_guid = $1`,
+	}
+	for _, query := range queries {
+		_, err = tx.Exec(query, guid)
+		if err != nil {
+			u.log.Error("Error deleting user", zap.String("op", op), zap.Error(err))
+
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM login_attempts WHERE key_type = $1 AND key = $2`,
+		LoginAttemptKeyLogin, attemptKey(LoginAttemptKeyLogin, login),
+	)
+	if err != nil {
+		u.log.Error("Error deleting user", zap.String("op", op), zap.Error(err))
+
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,148 @@
+package postgresRepo
+
+import (
+	"PetProjectGo/pkg/logging"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+var ErrUserTokenNotFound = errors.New("token not found or expired")
+
+// UserToken is a single-use secret sent to the user, e.g. to reset a
+// password. Only the hash of the secret is stored.
+type UserToken struct {
+	GUID      string     `db:"guid"`
+	UserGUID  string     `db:"user_guid"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt *time.Time `db:"created_at"`
+}
+
+type UserTokenRepoP struct {
+	log      *logging.Logger
+	postgres *sqlx.DB
+}
+
+func NewUserTokenRepoP(log *logging.Logger, postgres *sqlx.DB) *UserTokenRepoP {
+	return &UserTokenRepoP{
+		log:      log,
+		postgres: postgres,
+	}
+}
+
+func (u *UserTokenRepoP) AddToken(token *UserToken) error {
+	const op = "UserTokenRepoP.AddToken"
+
+	query := `INSERT INTO user_tokens (guid, user_guid, purpose, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := u.postgres.Exec(
+		query, token.GUID, token.UserGUID, token.Purpose, token.TokenHash, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		u.log.Error("Error adding user token", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// GetToken returns an unused, unexpired token without consuming it.
+func (u *UserTokenRepoP) GetToken(purpose string, tokenHash string, timeNow *time.Time) (*UserToken, error) {
+	const op = "UserTokenRepoP.GetToken"
+
+	var token UserToken
+
+	query := `SELECT guid, user_guid, purpose, token_hash, expires_at, used_at, created_at FROM user_tokens
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > $3`
+
+	err := u.postgres.Get(&token, query, purpose, tokenHash, timeNow)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserTokenNotFound
+		}
+		u.log.Error("Error getting user token", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// ConsumeToken marks an unused, unexpired token as used and returns it.
+// A token can be consumed only once even under concurrent requests.
+func (u *UserTokenRepoP) ConsumeToken(purpose string, tokenHash string, timeNow *time.Time) (*UserToken, error) {
+	const op = "UserTokenRepoP.ConsumeToken"
+
+	var token UserToken
+
+	query := `UPDATE user_tokens SET used_at = $1
+		WHERE purpose = $2 AND token_hash = $3 AND used_at IS NULL AND expires_at > $1
+		RETURNING guid, user_guid, purpose, token_hash, expires_at, used_at, created_at`
+
+	err := u.postgres.Get(&token, query, timeNow, purpose, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserTokenNotFound
+		}
+		u.log.Error("Error consuming user token", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetLastCreatedAt returns when the newest token of the user for the purpose
+// was issued, or nil if there is none.
+func (u *UserTokenRepoP) GetLastCreatedAt(userGuid string, purpose string) (*time.Time, error) {
+	const op = "UserTokenRepoP.GetLastCreatedAt"
+
+	var createdAt *time.Time
+
+	query := `SELECT MAX(created_at) FROM user_tokens WHERE user_guid = $1 AND purpose = $2`
+
+	err := u.postgres.QueryRow(query, userGuid, purpose).Scan(&createdAt)
+	if err != nil {
+		u.log.Error("Error getting last user token", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return createdAt, nil
+}
+
+// GetByUser returns every token of the user, newest first.
+func (u *UserTokenRepoP) GetByUser(userGuid string) ([]*UserToken, error) {
+	const op = "UserTokenRepoP.GetByUser"
+
+	var tokens []*UserToken
+
+	query := `SELECT guid, user_guid, purpose, token_hash, expires_at, used_at, created_at FROM user_tokens
+		WHERE user_guid = $1 ORDER BY created_at DESC`
+
+	err := u.postgres.Select(&tokens, query, userGuid)
+	if err != nil {
+		u.log.Error("Error getting user tokens", zap.String("op", op), zap.Error(err))
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// InvalidateTokens marks every unused token of the user for the purpose as used.
+func (u *UserTokenRepoP) InvalidateTokens(userGuid string, purpose string, timeNow *time.Time) error {
+	const op = "UserTokenRepoP.InvalidateTokens"
+
+	query := `UPDATE user_tokens SET used_at = $1 WHERE user_guid = $2 AND purpose = $3 AND used_at IS NULL`
+
+	_, err := u.postgres.Exec(query, timeNow, userGuid, purpose)
+	if err != nil {
+		u.log.Error("Error invalidating user tokens", zap.String("op", op), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
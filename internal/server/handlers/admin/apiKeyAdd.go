@@ -0,0 +1,70 @@
+package admin
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/server/handlers/user/apiKeys"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// HandlerUserAPIKeyAdd creates a key for another user, e.g. the account of
+// a service.
+type HandlerUserAPIKeyAdd struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerUserAPIKeyAdd(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerUserAPIKeyAdd {
+	return &HandlerUserAPIKeyAdd{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerUserAPIKeyAdd) Validate(req *apiKeys.RequestCreate) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerUserAPIKeyAdd) UserAPIKeyAddHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "admin.UserAPIKeyAddHandler"
+
+		var req apiKeys.RequestCreate
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		guid := chi.URLParam(r, "id")
+
+		key, err := h.userService.CreateAPIKey(guid, req.Name, req.Scopes, req.TTL())
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("Api key created by admin", zap.String("op", op), zap.String("guid", guid))
+
+		render.JSON(w, r, apiKeys.ResponseCreate{
+			Response:  resp.OK(),
+			NewAPIKey: key,
+		})
+	}
+}
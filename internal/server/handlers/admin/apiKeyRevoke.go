@@ -0,0 +1,45 @@
+package admin
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type HandlerUserAPIKeyRevoke struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerUserAPIKeyRevoke(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerUserAPIKeyRevoke {
+	return &HandlerUserAPIKeyRevoke{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerUserAPIKeyRevoke) UserAPIKeyRevokeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "admin.UserAPIKeyRevokeHandler"
+
+		guid := chi.URLParam(r, "id")
+		keyGuid := chi.URLParam(r, "keyId")
+
+		err := h.userService.RevokeAPIKey(guid, keyGuid)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("Api key revoked by admin", zap.String("op", op), zap.String("guid", guid), zap.String("key", keyGuid))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ResponseLogout struct {
+	resp.Response
+	Revoked int64 `json:"revoked"`
+}
+
+type HandlerUserLogout struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerUserLogout(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerUserLogout {
+	return &HandlerUserLogout{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerUserLogout) UserLogoutHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "admin.UserLogoutHandler"
+
+		guid := chi.URLParam(r, "id")
+
+		revoked, err := h.userService.ForceLogout(guid)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("User logged out by admin", zap.String("op", op), zap.String("guid", guid), zap.Int64("revoked", revoked))
+
+		render.JSON(w, r, ResponseLogout{
+			Response: resp.OK(),
+			Revoked:  revoked,
+		})
+	}
+}
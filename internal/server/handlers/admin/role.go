@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type RequestRole struct {
+	Role string `json:"role" validate:"required,oneof=admin seller customer"`
+}
+
+type ResponseRole struct {
+	resp.Response
+}
+
+type HandlerUserRole struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerUserRole(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerUserRole {
+	return &HandlerUserRole{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerUserRole) ValidateRole(req *RequestRole) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerUserRole) UserRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "admin.UserRoleHandler"
+
+		guid := chi.URLParam(r, "id")
+
+		var req RequestRole
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.ValidateRole(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		err = h.userService.SetRole(guid, req.Role)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("User role changed", zap.String("op", op), zap.String("guid", guid), zap.String("role", req.Role))
+
+		render.JSON(w, r, ResponseRole{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package admin
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type HandlerUserUnlock struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerUserUnlock(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerUserUnlock {
+	return &HandlerUserUnlock{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerUserUnlock) UserUnlockHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "admin.UserUnlockHandler"
+
+		guid := chi.URLParam(r, "id")
+
+		err := h.userService.UnlockUser(guid)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("User unlocked by admin", zap.String("op", op), zap.String("guid", guid))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
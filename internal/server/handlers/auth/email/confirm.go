@@ -0,0 +1,76 @@
+package email
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ConfirmRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type HandlerConfirm struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerConfirm(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerConfirm {
+	return &HandlerConfirm{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerConfirm) Validate(req *ConfirmRequest) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerConfirm) ConfirmHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "email.ConfirmHandler"
+
+		var req ConfirmRequest
+
+		// GET comes from the link in the verification message.
+		if r.Method == http.MethodGet {
+			req.Token = r.URL.Query().Get("token")
+		} else {
+			err := render.DecodeJSON(r.Body, &req)
+			if err != nil {
+				h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+				render.JSON(w, r, resp.Error(err.Error()))
+				return
+			}
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		err := h.userService.ConfirmEmail(req.Token)
+		if errors.Is(err, services.InvalidVerificationToken) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err != nil {
+			h.log.Error("Failed to confirm email", zap.String("op", op), zap.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to confirm email"))
+			return
+		}
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,66 @@
+package email
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ResendRequest struct {
+	Login string `json:"login" validate:"required"`
+}
+
+type HandlerResend struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerResend(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerResend {
+	return &HandlerResend{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerResend) Validate(req *ResendRequest) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerResend) ResendHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "email.ResendHandler"
+
+		var req ResendRequest
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		err = h.userService.ResendEmailVerification(req.Login)
+		if err != nil {
+			h.log.Error("Failed to resend email verification", zap.String("op", op), zap.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to resend email verification"))
+			return
+		}
+
+		// The same answer is given whether a message was sent or not.
+		render.JSON(w, r, resp.OK())
+	}
+}
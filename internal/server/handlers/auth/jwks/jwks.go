@@ -0,0 +1,32 @@
+package jwks
+
+import (
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type HandlerJWKS struct {
+	log        *logging.Logger
+	keyService *services.KeyService
+}
+
+func NewHandlerJWKS(
+	log *logging.Logger,
+	keyService *services.KeyService,
+) *HandlerJWKS {
+	return &HandlerJWKS{
+		log:        log,
+		keyService: keyService,
+	}
+}
+
+// JWKSHandler serves the public keys in the standard JWK Set format, so it
+// is not wrapped into the usual response envelope.
+func (h *HandlerJWKS) JWKSHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		render.JSON(w, r, h.keyService.JWKS())
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"PetProjectGo/pkg/logging"
 	"github.com/go-chi/render"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"net/http"
 )
@@ -15,6 +16,7 @@ import (
 type Request struct {
 	Login    string `json:"login" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	Device   string `json:"device,omitempty"`
 }
 
 type Response struct {
@@ -24,6 +26,14 @@ type Response struct {
 	User         *models.User `json:"user"`
 }
 
+// ResponseTwoFactor is returned instead of Response when the user has 2FA
+// enabled. The token is sent to /auth/login/2fa together with a code.
+type ResponseTwoFactor struct {
+	resp.Response
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	TwoFactorToken    string `json:"two_factor_token"`
+}
+
 type HandlerLogin struct {
 	log         *logging.Logger
 	userService *services.UserService
@@ -70,7 +80,37 @@ func (h *HandlerLogin) LoginHandler() http.HandlerFunc {
 			return
 		}
 
-		t, user, err := h.userService.Login(req.Login, req.Password)
+		client := &services.ClientInfo{
+			Device:    req.Device,
+			IP:        handlers.ClientIP(r),
+			UserAgent: r.UserAgent(),
+		}
+
+		t, rt, user, err := h.userService.Login(req.Login, req.Password, client)
+		if errors.Is(err, services.ErrAccountLocked) || errors.Is(err, services.ErrTooManyAttempts) {
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		var twoFactorErr *services.TwoFactorRequiredError
+		if errors.As(err, &twoFactorErr) {
+			render.JSON(w, r, ResponseTwoFactor{
+				Response:          resp.OK(),
+				TwoFactorRequired: true,
+				TwoFactorToken:    twoFactorErr.Token,
+			})
+			return
+		}
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if errors.Is(err, services.ErrPasswordChangeRequired) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.ErrorCode(resp.CodePasswordChangeRequired, err.Error()))
+			return
+		}
 		if err != nil {
 			render.JSON(w, r, resp.Error(err.Error()))
 			return
@@ -81,7 +121,7 @@ func (h *HandlerLogin) LoginHandler() http.HandlerFunc {
 		response := Response{
 			Response:     resp.OK(),
 			Token:        t,
-			RefreshToken: user.RefreshToken,
+			RefreshToken: rt,
 			User:         user,
 		}
 
@@ -0,0 +1,88 @@
+package login
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type RequestTwoFactor struct {
+	TwoFactorToken string `json:"two_factor_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+	Device         string `json:"device,omitempty"`
+}
+
+type HandlerLoginTwoFactor struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerLoginTwoFactor(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerLoginTwoFactor {
+	return &HandlerLoginTwoFactor{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerLoginTwoFactor) Validate(req *RequestTwoFactor) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerLoginTwoFactor) LoginTwoFactorHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "login.LoginTwoFactorHandler"
+
+		var req RequestTwoFactor
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		client := &services.ClientInfo{
+			Device:    req.Device,
+			IP:        handlers.ClientIP(r),
+			UserAgent: r.UserAgent(),
+		}
+
+		t, rt, user, err := h.userService.LoginTwoFactor(req.TwoFactorToken, req.Code, client)
+		if errors.Is(err, services.ErrAccountLocked) || errors.Is(err, services.ErrTooManyAttempts) {
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		w.Header().Set("Authorization", "Bearer "+t)
+
+		response := Response{
+			Response:     resp.OK(),
+			Token:        t,
+			RefreshToken: rt,
+			User:         user,
+		}
+
+		h.log.Info("User logged with two-factor code", zap.String("op", op), zap.Any("user", user))
+
+		render.JSON(w, r, response)
+	}
+}
@@ -0,0 +1,86 @@
+package password
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ResetConfirmRequest struct {
+	Token             string `json:"token" validate:"required"`
+	Password          string `json:"password" validate:"required"`
+	ConfirmedPassword string `json:"confirmed_password" validate:"required,eqfield=Password"`
+}
+
+type HandlerResetConfirm struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerResetConfirm(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerResetConfirm {
+	return &HandlerResetConfirm{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerResetConfirm) Validate(req *ResetConfirmRequest) []*handlers.ValidationError {
+	errs := handlers.CreateValidationErrorsResp(req)
+
+	// The login is not known here, the service checks the rest of the policy.
+	violations := h.userService.CheckPassword(req.Password, "")
+	errs = append(errs, handlers.CreatePasswordErrorsResp("ResetConfirmRequest.Password", violations)...)
+
+	return errs
+}
+
+func (h *HandlerResetConfirm) ResetConfirmHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "password.ResetConfirmHandler"
+
+		var req ResetConfirmRequest
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		err = h.userService.ConfirmPasswordReset(req.Token, req.Password)
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			render.JSON(w, r, resp.Error(handlers.CreatePasswordErrorsResp("ResetConfirmRequest.Password", policyErr.Violations)))
+			return
+		}
+		if errors.Is(err, services.InvalidResetToken) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err != nil {
+			h.log.Error("Failed to reset password", zap.String("op", op), zap.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to reset password"))
+			return
+		}
+
+		h.log.Info("Password reset confirmed", zap.String("op", op))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
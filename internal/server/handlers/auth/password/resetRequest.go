@@ -0,0 +1,69 @@
+package password
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ResetRequestRequest struct {
+	Login string `json:"login" validate:"required"`
+}
+
+type HandlerResetRequest struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerResetRequest(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerResetRequest {
+	return &HandlerResetRequest{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerResetRequest) Validate(req *ResetRequestRequest) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerResetRequest) ResetRequestHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "password.ResetRequestHandler"
+
+		var req ResetRequestRequest
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		// Only a failed login lookup is an error; the token is issued and
+		// mailed in the background, as its failures would tell that the login
+		// exists.
+		err = h.userService.RequestPasswordReset(req.Login)
+		if err != nil {
+			h.log.Error("Failed to request password reset", zap.String("op", op), zap.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to request password reset"))
+			return
+		}
+
+		// The same answer is given whether the login exists or not.
+		render.JSON(w, r, resp.OK())
+	}
+}
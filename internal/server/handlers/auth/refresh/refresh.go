@@ -6,13 +6,14 @@ import (
 	resp "PetProjectGo/internal/server/handlers/response"
 	"PetProjectGo/internal/services"
 	"PetProjectGo/pkg/logging"
+	"errors"
 	"github.com/go-chi/render"
 	"go.uber.org/zap"
 	"net/http"
 )
 
 type Request struct {
-	GUID string `json:"id" validate:"required"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 type Response struct {
@@ -60,7 +61,12 @@ func (h *HandlerRefresh) RefreshHandler() http.HandlerFunc {
 			return
 		}
 
-		t, user, err := h.userService.Refresh(req.GUID)
+		t, rt, user, err := h.userService.Refresh(req.RefreshToken)
+		if code := refreshErrorCode(err); code != "" {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.ErrorCode(code, err.Error()))
+			return
+		}
 		if err != nil {
 			render.JSON(w, r, resp.Error(err.Error()))
 			return
@@ -69,7 +75,7 @@ func (h *HandlerRefresh) RefreshHandler() http.HandlerFunc {
 		response := Response{
 			Response:     resp.OK(),
 			Token:        t,
-			RefreshToken: user.RefreshToken,
+			RefreshToken: rt,
 			User:         user,
 		}
 
@@ -78,3 +84,18 @@ func (h *HandlerRefresh) RefreshHandler() http.HandlerFunc {
 		render.JSON(w, r, response)
 	}
 }
+
+// refreshErrorCode returns the code of an error meaning the client has to log
+// in again, or "" for other errors.
+func refreshErrorCode(err error) string {
+	switch {
+	case errors.Is(err, services.RefreshTokenExpired):
+		return resp.CodeRefreshTokenExpired
+	case errors.Is(err, services.RefreshTokenReused):
+		return resp.CodeRefreshTokenReused
+	case errors.Is(err, services.InvalidRefreshToken):
+		return resp.CodeRefreshTokenInvalid
+	default:
+		return ""
+	}
+}
@@ -7,15 +7,16 @@ import (
 	resp "PetProjectGo/internal/server/handlers/response"
 	"PetProjectGo/internal/services"
 	"PetProjectGo/pkg/logging"
-	"fmt"
 	"github.com/go-chi/render"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"net/http"
 )
 
 type Request struct {
 	Login             string `json:"login" validate:"required"`
+	Email             string `json:"email" validate:"omitempty,email"`
 	Password          string `json:"password" validate:"required"`
 	ConfirmedPassword string `json:"confirmed_password" validate:"required,eqfield=Password"`
 	Name              string `json:"name,omitempty"`
@@ -45,17 +46,17 @@ func NewHandlerRegister(
 	}
 }
 
-func (h *HandlerRegister) Validate(req *Request, passwordMinLength int) []*handlers.ValidationError {
+func (h *HandlerRegister) Validate(req *Request) []*handlers.ValidationError {
 	errs := handlers.CreateValidationErrorsResp(req)
 
-	if len(req.Password) < passwordMinLength {
-		var element handlers.ValidationError
-		element.Field = "Request.Password"
-		element.Tag = fmt.Sprintf("min length password: %d", passwordMinLength)
-		element.Value = ""
-		errs = append(errs, &element)
+	// The email is only required when it has to be verified before logging in.
+	if h.cfg.EmailVerificationRequired && req.Email == "" {
+		errs = append(errs, &handlers.ValidationError{Field: "Request.Email", Tag: "required"})
 	}
 
+	violations := h.userService.CheckPassword(req.Password, req.Login)
+	errs = append(errs, handlers.CreatePasswordErrorsResp("Request.Password", violations)...)
+
 	return errs
 }
 
@@ -72,7 +73,7 @@ func (h *HandlerRegister) RegisterHandler() http.HandlerFunc {
 			return
 		}
 
-		errs := h.Validate(&req, h.cfg.PasswordMinLength)
+		errs := h.Validate(&req)
 		if len(errs) != 0 {
 			render.JSON(w, r, resp.Error(errs))
 			return
@@ -87,6 +88,11 @@ func (h *HandlerRegister) RegisterHandler() http.HandlerFunc {
 		}
 
 		user, err := h.userService.Register(newUser)
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			render.JSON(w, r, resp.Error(handlers.CreatePasswordErrorsResp("Request.Password", policyErr.Violations)))
+			return
+		}
 		if err != nil {
 			render.JSON(w, r, resp.Error(err.Error()))
 			return
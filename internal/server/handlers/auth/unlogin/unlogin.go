@@ -1,20 +1,15 @@
 package unlogin
 
 import (
-	"PetProjectGo/internal/server/handlers"
 	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
 	"PetProjectGo/internal/services"
 	"PetProjectGo/pkg/logging"
 	"github.com/go-chi/render"
-	"github.com/mitchellh/mapstructure"
 	"go.uber.org/zap"
 	"net/http"
 )
 
-type Request struct {
-	GUID string `json:"id" validate:"required"`
-}
-
 type Response struct {
 	resp.Response
 }
@@ -34,38 +29,13 @@ func NewHandlerUnLogin(
 	}
 }
 
-func (h *HandlerUnLogin) Validate(req *Request) []*handlers.ValidationError {
-	return handlers.CreateValidationErrorsResp(req)
-}
-
 func (h *HandlerUnLogin) UnLoginHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "login.LoginHandler"
-
-		var req Request
+		const op = "unlogin.UnLoginHandler"
 
-		err := render.DecodeJSON(r.Body, &req)
-		if err != nil {
-			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
-			render.JSON(w, r, resp.Error(err.Error()))
-			return
-		}
-
-		errs := h.Validate(&req)
-		if len(errs) != 0 {
-			render.JSON(w, r, resp.Error(errs))
-			return
-		}
-
-		var newUser *services.NewUserM
-		err = mapstructure.Decode(req, &newUser)
-		if err != nil {
-			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
-			render.JSON(w, r, resp.Error(err.Error()))
-			return
-		}
+		userInfo := mwAuth.GetUserInfo(r.Context())
 
-		err = h.userService.UnLogin(req.GUID)
+		err := h.userService.UnLogin(userInfo)
 		if err != nil {
 			render.JSON(w, r, resp.Error(err.Error()))
 			return
@@ -75,7 +45,7 @@ func (h *HandlerUnLogin) UnLoginHandler() http.HandlerFunc {
 			Response: resp.OK(),
 		}
 
-		h.log.Info("User unlogged", zap.String("op", op), zap.String("guid", req.GUID))
+		h.log.Info("User unlogged", zap.String("op", op), zap.String("guid", userInfo.ID))
 
 		render.JSON(w, r, response)
 	}
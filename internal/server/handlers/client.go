@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the host part of the request remote address, which the
+// real IP middleware sets to the forwarded client IP behind a trusted proxy.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
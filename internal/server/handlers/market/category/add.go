@@ -16,6 +16,13 @@ type RequestCategory struct {
 	Name string `json:"name" validate:"required"`
 }
 
+// RequestCategoryAdd adds a category under the category ParentID, or at the
+// root without one.
+type RequestCategoryAdd struct {
+	Name     string `json:"name" validate:"required"`
+	ParentID string `json:"parent_id"`
+}
+
 type ResponseCategory struct {
 	resp.Response
 	Categories []*models.Category `json:"categories"`
@@ -37,7 +44,7 @@ func NewHandlerCategoryAdd(
 	}
 }
 
-func (h *HandlerCategoryAdd) ValidateCategory(req *RequestCategory) []*handlers.ValidationError {
+func (h *HandlerCategoryAdd) ValidateCategory(req *RequestCategoryAdd) []*handlers.ValidationError {
 	return handlers.CreateValidationErrorsResp(req)
 }
 
@@ -45,7 +52,7 @@ func (h *HandlerCategoryAdd) AddCategoryHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "category.AddCategoryHandler"
 
-		var req RequestCategory
+		var req RequestCategoryAdd
 
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
@@ -59,7 +66,7 @@ func (h *HandlerCategoryAdd) AddCategoryHandler() http.HandlerFunc {
 			render.JSON(w, r, resp.Error(errs))
 			return
 		}
-		err = h.marketCategoryService.AddCategory(req.Name)
+		err = h.marketCategoryService.AddCategory(req.Name, req.ParentID)
 		if err != nil {
 			render.JSON(w, r, resp.Error(err.Error()))
 			return
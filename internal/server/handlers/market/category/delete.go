@@ -0,0 +1,62 @@
+package category
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+)
+
+type ResponseCategoryDelete struct {
+	resp.Response
+	*services.DeleteCategoryResult
+}
+
+type HandlerCategoryDelete struct {
+	log                   *logging.Logger
+	marketCategoryService *services.MarketCategoryService
+}
+
+func NewHandlerCategoryDelete(
+	log *logging.Logger,
+	marketCategoryService *services.MarketCategoryService,
+) *HandlerCategoryDelete {
+	return &HandlerCategoryDelete{
+		log:                   log,
+		marketCategoryService: marketCategoryService,
+	}
+}
+
+// DeleteCategoryHandler deletes the category. A category with products is
+// only deleted with ?cascade=true, deleting them, or ?move_to=<category id>.
+func (h *HandlerCategoryDelete) DeleteCategoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var opts services.DeleteCategoryOptions
+		if cascade := query.Get("cascade"); cascade != "" {
+			var err error
+			opts.Cascade, err = strconv.ParseBool(cascade)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.ErrorCode(resp.CodeInvalidDeleteMode, "cascade must be true or false"))
+				return
+			}
+		}
+		opts.MoveTo = query.Get("move_to")
+
+		result, err := h.marketCategoryService.DeleteCategory(chi.URLParam(r, "id"), opts)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseCategoryDelete{
+			Response:             resp.OK(),
+			DeleteCategoryResult: result,
+		})
+	}
+}
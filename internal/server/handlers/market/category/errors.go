@@ -0,0 +1,38 @@
+package category
+
+import (
+	"PetProjectGo/internal/repository/mongoRepo"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"errors"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+// renderError responds with the status and code of a category error.
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := http.StatusInternalServerError, ""
+	switch {
+	case errors.Is(err, mongoRepo.ErrCategoryNotFound):
+		status, code = http.StatusNotFound, resp.CodeCategoryNotFound
+	case errors.Is(err, mongoRepo.DuplicateCategoryNameError):
+		status, code = http.StatusConflict, resp.CodeCategoryNameTaken
+	case errors.Is(err, services.ErrCategoryNotEmpty):
+		status, code = http.StatusConflict, resp.CodeCategoryNotEmpty
+	case errors.Is(err, services.ErrInvalidDeleteMode):
+		status, code = http.StatusBadRequest, resp.CodeInvalidDeleteMode
+	case errors.Is(err, services.ErrMoveTargetNotFound):
+		status, code = http.StatusBadRequest, resp.CodeMoveTargetNotFound
+	case errors.Is(err, services.ErrMoveTargetSame):
+		status, code = http.StatusBadRequest, resp.CodeMoveTargetInvalid
+	case errors.Is(err, services.ErrCategoryHasChildren):
+		status, code = http.StatusConflict, resp.CodeCategoryHasChildren
+	case errors.Is(err, services.ErrParentCategoryNotFound):
+		status, code = http.StatusBadRequest, resp.CodeParentCategoryNotFound
+	case errors.Is(err, services.ErrCategoryCycle):
+		status, code = http.StatusBadRequest, resp.CodeCategoryCycle
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, resp.ErrorCode(code, err.Error()))
+}
@@ -0,0 +1,46 @@
+package category
+
+import (
+	"PetProjectGo/internal/models"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type ResponseCategoryOne struct {
+	resp.Response
+	Category *models.Category `json:"category"`
+}
+
+type HandlerCategoryGet struct {
+	log                   *logging.Logger
+	marketCategoryService *services.MarketCategoryService
+}
+
+func NewHandlerCategoryGet(
+	log *logging.Logger,
+	marketCategoryService *services.MarketCategoryService,
+) *HandlerCategoryGet {
+	return &HandlerCategoryGet{
+		log:                   log,
+		marketCategoryService: marketCategoryService,
+	}
+}
+
+func (h *HandlerCategoryGet) GetCategoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, err := h.marketCategoryService.GetByGuid(chi.URLParam(r, "id"))
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseCategoryOne{
+			Response: resp.OK(),
+			Category: category,
+		})
+	}
+}
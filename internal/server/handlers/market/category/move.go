@@ -0,0 +1,59 @@
+package category
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// RequestCategoryMove moves a category under the category ParentID, or to
+// the root without one.
+type RequestCategoryMove struct {
+	ParentID string `json:"parent_id"`
+}
+
+type HandlerCategoryMove struct {
+	log                   *logging.Logger
+	marketCategoryService *services.MarketCategoryService
+}
+
+func NewHandlerCategoryMove(
+	log *logging.Logger,
+	marketCategoryService *services.MarketCategoryService,
+) *HandlerCategoryMove {
+	return &HandlerCategoryMove{
+		log:                   log,
+		marketCategoryService: marketCategoryService,
+	}
+}
+
+// MoveCategoryHandler moves the category together with its subcategories.
+func (h *HandlerCategoryMove) MoveCategoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "category.MoveCategoryHandler"
+
+		var req RequestCategoryMove
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		category, err := h.marketCategoryService.MoveCategory(chi.URLParam(r, "id"), req.ParentID)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseCategoryOne{
+			Response: resp.OK(),
+			Category: category,
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package category
+
+import (
+	"PetProjectGo/internal/models"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type ResponseCategoryPath struct {
+	resp.Response
+	Path []*models.Category `json:"path"`
+}
+
+type HandlerCategoryPath struct {
+	log                   *logging.Logger
+	marketCategoryService *services.MarketCategoryService
+}
+
+func NewHandlerCategoryPath(
+	log *logging.Logger,
+	marketCategoryService *services.MarketCategoryService,
+) *HandlerCategoryPath {
+	return &HandlerCategoryPath{
+		log:                   log,
+		marketCategoryService: marketCategoryService,
+	}
+}
+
+// CategoryPathHandler responds with the breadcrumbs of the category, from
+// the root category down to it.
+func (h *HandlerCategoryPath) CategoryPathHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path, err := h.marketCategoryService.GetCategoryPath(chi.URLParam(r, "id"))
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseCategoryPath{
+			Response: resp.OK(),
+			Path:     path,
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package category
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type HandlerCategoryRename struct {
+	log                   *logging.Logger
+	marketCategoryService *services.MarketCategoryService
+}
+
+func NewHandlerCategoryRename(
+	log *logging.Logger,
+	marketCategoryService *services.MarketCategoryService,
+) *HandlerCategoryRename {
+	return &HandlerCategoryRename{
+		log:                   log,
+		marketCategoryService: marketCategoryService,
+	}
+}
+
+func (h *HandlerCategoryRename) ValidateCategory(req *RequestCategory) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerCategoryRename) RenameCategoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "category.RenameCategoryHandler"
+
+		var req RequestCategory
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.ValidateCategory(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		guid := chi.URLParam(r, "id")
+
+		category, err := h.marketCategoryService.RenameCategory(guid, req.Name)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		h.log.Info("Category renamed", zap.String("op", op), zap.String("guid", guid), zap.String("name", req.Name))
+
+		render.JSON(w, r, ResponseCategoryOne{
+			Response: resp.OK(),
+			Category: category,
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package category
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type ResponseCategoryTree struct {
+	resp.Response
+	Categories []*services.CategoryNode `json:"categories"`
+}
+
+type HandlerCategoryTree struct {
+	log                   *logging.Logger
+	marketCategoryService *services.MarketCategoryService
+}
+
+func NewHandlerCategoryTree(
+	log *logging.Logger,
+	marketCategoryService *services.MarketCategoryService,
+) *HandlerCategoryTree {
+	return &HandlerCategoryTree{
+		log:                   log,
+		marketCategoryService: marketCategoryService,
+	}
+}
+
+func (h *HandlerCategoryTree) CategoryTreeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tree, err := h.marketCategoryService.GetCategoryTree()
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseCategoryTree{
+			Response:   resp.OK(),
+			Categories: tree,
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package product
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type HandlerProductDelete struct {
+	log                  *logging.Logger
+	marketProductService *services.MarketProductService
+}
+
+func NewHandlerProductDelete(
+	log *logging.Logger,
+	marketProductService *services.MarketProductService,
+) *HandlerProductDelete {
+	return &HandlerProductDelete{
+		log:                  log,
+		marketProductService: marketProductService,
+	}
+}
+
+func (h *HandlerProductDelete) DeleteProductHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "product.DeleteProductHandler"
+
+		guid := chi.URLParam(r, "id")
+
+		err := h.marketProductService.DeleteProduct(guid)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		h.log.Info("Product deleted", zap.String("op", op), zap.String("guid", guid))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,25 @@
+package product
+
+import (
+	"PetProjectGo/internal/repository/mongoRepo"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"errors"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+// renderError responds with the status and code of a product error.
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := http.StatusInternalServerError, ""
+	switch {
+	case errors.Is(err, mongoRepo.ErrProductNotFound):
+		status, code = http.StatusNotFound, resp.CodeProductNotFound
+	case errors.Is(err, mongoRepo.DuplicateProductNameError):
+		status, code = http.StatusConflict, resp.CodeProductNameTaken
+	case errors.Is(err, mongoRepo.ErrCategoryNotFound):
+		status, code = http.StatusBadRequest, resp.CodeCategoryNotFound
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, resp.ErrorCode(code, err.Error()))
+}
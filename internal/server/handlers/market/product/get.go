@@ -0,0 +1,40 @@
+package product
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type HandlerProductGet struct {
+	log                  *logging.Logger
+	marketProductService *services.MarketProductService
+}
+
+func NewHandlerProductGet(
+	log *logging.Logger,
+	marketProductService *services.MarketProductService,
+) *HandlerProductGet {
+	return &HandlerProductGet{
+		log:                  log,
+		marketProductService: marketProductService,
+	}
+}
+
+func (h *HandlerProductGet) GetProductHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		product, err := h.marketProductService.GetProduct(chi.URLParam(r, "id"))
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseProduct{
+			Response: resp.OK(),
+			Product:  product,
+		})
+	}
+}
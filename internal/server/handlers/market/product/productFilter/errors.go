@@ -0,0 +1,29 @@
+package productFilter
+
+import (
+	"PetProjectGo/internal/repository/mongoRepo"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"errors"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+// renderError responds with the status and code of a listing or search error.
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := http.StatusInternalServerError, ""
+	switch {
+	case errors.Is(err, services.ErrInvalidCursor):
+		status, code = http.StatusBadRequest, resp.CodeInvalidCursor
+	case errors.Is(err, services.ErrInvalidSort),
+		errors.Is(err, services.ErrInvalidLimit),
+		errors.Is(err, services.ErrInvalidPriceRange),
+		errors.Is(err, services.ErrInvalidSearch):
+		status, code = http.StatusBadRequest, resp.CodeInvalidQuery
+	case errors.Is(err, mongoRepo.ErrCategoryNotFound):
+		status, code = http.StatusNotFound, resp.CodeCategoryNotFound
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, resp.ErrorCode(code, err.Error()))
+}
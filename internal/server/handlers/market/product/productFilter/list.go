@@ -0,0 +1,124 @@
+package productFilter
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"fmt"
+	"github.com/go-chi/render"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type ResponseProducts struct {
+	resp.Response
+	*services.ProductPage
+}
+
+type HandlerProductList struct {
+	log                  *logging.Logger
+	marketProductService *services.MarketProductService
+}
+
+func NewHandlerProductList(
+	log *logging.Logger,
+	marketProductService *services.MarketProductService,
+) *HandlerProductList {
+	return &HandlerProductList{
+		log:                  log,
+		marketProductService: marketProductService,
+	}
+}
+
+// ListProductsHandler lists products page by page. Query parameters:
+// category_id, include_descendants, to list the subcategories too,
+// min_price, max_price, in_stock, name_prefix, sort (name, price or
+// created, "-" prefixed for descending order), limit and cursor, the
+// next_cursor of the page before.
+func (h *HandlerProductList) ListProductsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := parseListQuery(r.URL.Query())
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ErrorCode(resp.CodeInvalidQuery, err.Error()))
+			return
+		}
+
+		page, err := h.marketProductService.ListProducts(list)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseProducts{
+			Response:    resp.OK(),
+			ProductPage: page,
+		})
+	}
+}
+
+func parseListQuery(query url.Values) (*services.ProductListM, error) {
+	list := &services.ProductListM{
+		CategoryGuid: query.Get("category_id"),
+		NamePrefix:   query.Get("name_prefix"),
+		Sort:         query.Get("sort"),
+		Cursor:       query.Get("cursor"),
+	}
+
+	var err error
+	list.MinPrice, err = parseIntParam(query, "min_price")
+	if err != nil {
+		return nil, err
+	}
+	list.MaxPrice, err = parseIntParam(query, "max_price")
+	if err != nil {
+		return nil, err
+	}
+	limit, err := parseIntParam(query, "limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit != nil {
+		list.Limit = *limit
+	}
+
+	list.InStock, err = parseBoolParam(query, "in_stock")
+	if err != nil {
+		return nil, err
+	}
+	list.IncludeDescendants, err = parseBoolParam(query, "include_descendants")
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func parseIntParam(query url.Values, name string) (*int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", name)
+	}
+
+	return &n, nil
+}
+
+func parseBoolParam(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,79 @@
+package productFilter
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"net/http"
+	"net/url"
+)
+
+type ResponseProductSearch struct {
+	resp.Response
+	*services.ProductSearchPage
+}
+
+type HandlerProductSearch struct {
+	log                  *logging.Logger
+	marketProductService *services.MarketProductService
+}
+
+func NewHandlerProductSearch(
+	log *logging.Logger,
+	marketProductService *services.MarketProductService,
+) *HandlerProductSearch {
+	return &HandlerProductSearch{
+		log:                  log,
+		marketProductService: marketProductService,
+	}
+}
+
+// SearchProductsHandler searches products by name and description. Query
+// parameters: q, the search, category_id, limit and offset, the next_offset
+// of the page before.
+func (h *HandlerProductSearch) SearchProductsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		search, err := parseSearchQuery(r.URL.Query())
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ErrorCode(resp.CodeInvalidQuery, err.Error()))
+			return
+		}
+
+		page, err := h.marketProductService.SearchProducts(search)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, ResponseProductSearch{
+			Response:          resp.OK(),
+			ProductSearchPage: page,
+		})
+	}
+}
+
+func parseSearchQuery(query url.Values) (*services.ProductSearchM, error) {
+	search := &services.ProductSearchM{
+		Query:        query.Get("q"),
+		CategoryGuid: query.Get("category_id"),
+	}
+
+	limit, err := parseIntParam(query, "limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit != nil {
+		search.Limit = *limit
+	}
+	offset, err := parseIntParam(query, "offset")
+	if err != nil {
+		return nil, err
+	}
+	if offset != nil {
+		search.Offset = *offset
+	}
+
+	return search, nil
+}
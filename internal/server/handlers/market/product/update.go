@@ -0,0 +1,84 @@
+package product
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// RequestProductUpdate holds the fields to change; omitted ones are kept.
+// A given field is validated as in RequestProduct: "required" does not look
+// through pointers, so min=1 and ne=0 reject the same empty values.
+type RequestProductUpdate struct {
+	CategoryId  *string `json:"category_id,omitempty" validate:"omitnil,min=1"`
+	Name        *string `json:"name,omitempty" validate:"omitnil,min=1"`
+	Price       *int    `json:"price,omitempty" validate:"omitnil,ne=0"`
+	Description *string `json:"description,omitempty" validate:"omitnil,min=1"`
+	Quantity    *int    `json:"quantity,omitempty"`
+}
+
+type HandlerProductUpdate struct {
+	log                  *logging.Logger
+	marketProductService *services.MarketProductService
+}
+
+func NewHandlerProductUpdate(
+	log *logging.Logger,
+	marketProductService *services.MarketProductService,
+) *HandlerProductUpdate {
+	return &HandlerProductUpdate{
+		log:                  log,
+		marketProductService: marketProductService,
+	}
+}
+
+func (h *HandlerProductUpdate) ValidateProduct(req *RequestProductUpdate) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerProductUpdate) UpdateProductHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "product.UpdateProductHandler"
+
+		var req RequestProductUpdate
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.ValidateProduct(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		guid := chi.URLParam(r, "id")
+
+		product, err := h.marketProductService.UpdateProduct(guid, &services.ProductUpdateM{
+			CategoryGuid: req.CategoryId,
+			Name:         req.Name,
+			Description:  req.Description,
+			Price:        req.Price,
+			Quantity:     req.Quantity,
+		})
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		h.log.Info("Product updated", zap.String("op", op), zap.String("guid", guid))
+
+		render.JSON(w, r, ResponseProduct{
+			Response: resp.OK(),
+			Product:  product,
+		})
+	}
+}
@@ -2,8 +2,43 @@ package response
 
 import "PetProjectGo/internal/config"
 
+// Codes tell clients which authentication failure they hit, so they know
+// whether to refresh the access token or to log in again.
+const (
+	CodeTokenMissing           = "token_missing"
+	CodeTokenExpired           = "token_expired"
+	CodeTokenInvalid           = "token_invalid"
+	CodeTokenRevoked           = "token_revoked"
+	CodeSessionEnded           = "session_ended"
+	CodeRefreshTokenExpired    = "refresh_token_expired"
+	CodeRefreshTokenInvalid    = "refresh_token_invalid"
+	CodeRefreshTokenReused     = "refresh_token_reused"
+	CodeAPIKeyExpired          = "api_key_expired"
+	CodeInsufficientScope      = "insufficient_scope"
+	CodeSessionRequired        = "session_required"
+	CodePasswordChangeRequired = "password_change_required"
+)
+
+// Codes of the errors of the market routes.
+const (
+	CodeCategoryNotFound       = "category_not_found"
+	CodeCategoryNameTaken      = "category_name_taken"
+	CodeCategoryNotEmpty       = "category_not_empty"
+	CodeInvalidDeleteMode      = "invalid_delete_mode"
+	CodeMoveTargetNotFound     = "move_target_not_found"
+	CodeMoveTargetInvalid      = "move_target_invalid"
+	CodeCategoryHasChildren    = "category_has_children"
+	CodeParentCategoryNotFound = "parent_category_not_found"
+	CodeCategoryCycle          = "category_cycle"
+	CodeProductNotFound        = "product_not_found"
+	CodeProductNameTaken       = "product_name_taken"
+	CodeInvalidQuery           = "invalid_query"
+	CodeInvalidCursor          = "invalid_cursor"
+)
+
 type Response struct {
 	Status string      `json:"status"`
+	Code   string      `json:"code,omitempty"`
 	Error  interface{} `json:"error,omitempty"`
 }
 
@@ -19,3 +54,11 @@ func Error(err interface{}) Response {
 		Error:  err,
 	}
 }
+
+func ErrorCode(code string, err interface{}) Response {
+	return Response{
+		Status: config.StatusError,
+		Code:   code,
+		Error:  err,
+	}
+}
@@ -0,0 +1,39 @@
+package account
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type HandlerCancelDelete struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerCancelDelete(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerCancelDelete {
+	return &HandlerCancelDelete{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerCancelDelete) CancelDeleteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		err := h.userService.CancelAccountDeletion(userInfo.ID)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,81 @@
+package account
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type RequestDelete struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type ResponseDelete struct {
+	resp.Response
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+}
+
+type HandlerDelete struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerDelete(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerDelete {
+	return &HandlerDelete{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerDelete) Validate(req *RequestDelete) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerDelete) DeleteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "account.DeleteHandler"
+
+		var req RequestDelete
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		deleteAt, err := h.userService.RequestAccountDeletion(userInfo, req.Password)
+		if errors.Is(err, services.ErrAccountLocked) || errors.Is(err, services.ErrTooManyAttempts) {
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, ResponseDelete{
+			Response:            resp.OK(),
+			DeletionScheduledAt: deleteAt,
+		})
+	}
+}
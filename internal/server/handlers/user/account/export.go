@@ -0,0 +1,49 @@
+package account
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"fmt"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type HandlerExport struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerExport(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerExport {
+	return &HandlerExport{
+		log:         log,
+		userService: userService,
+	}
+}
+
+// ExportHandler sends the data export as a JSON file download.
+func (h *HandlerExport) ExportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "account.ExportHandler"
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		export, err := h.userService.ExportUserData(userInfo.ID)
+		if err != nil {
+			h.log.Error("Failed to export user data", zap.String("op", op), zap.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to export user data"))
+			return
+		}
+
+		h.log.Info("User data exported", zap.String("op", op), zap.String("guid", userInfo.ID))
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-data-%s.json"`, userInfo.ID))
+		render.JSON(w, r, export)
+	}
+}
@@ -0,0 +1,87 @@
+package apiKeys
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// RequestCreate describes a new key. Without ExpiresInDays the key lives for
+// app.api_keys.default_ttl.
+type RequestCreate struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" validate:"omitempty,min=1"`
+}
+
+// TTL returns the asked lifetime of the key, 0 for the default one.
+func (r *RequestCreate) TTL() time.Duration {
+	return time.Duration(r.ExpiresInDays) * 24 * time.Hour
+}
+
+// ResponseCreate holds the key itself, shown only this once.
+type ResponseCreate struct {
+	resp.Response
+	*services.NewAPIKey
+}
+
+type HandlerCreate struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerCreate(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerCreate {
+	return &HandlerCreate{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerCreate) Validate(req *RequestCreate) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerCreate) CreateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "apiKeys.CreateHandler"
+
+		var req RequestCreate
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		key, err := h.userService.CreateAPIKey(userInfo.ID, req.Name, req.Scopes, req.TTL())
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("User created api key", zap.String("op", op), zap.String("guid", userInfo.ID))
+
+		render.JSON(w, r, ResponseCreate{
+			Response:  resp.OK(),
+			NewAPIKey: key,
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package apiKeys
+
+import (
+	"PetProjectGo/internal/repository/postgresRepo"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type ResponseList struct {
+	resp.Response
+	APIKeys []*postgresRepo.APIKey `json:"api_keys"`
+}
+
+type HandlerList struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerList(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerList {
+	return &HandlerList{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerList) ListHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		keys, err := h.userService.GetAPIKeys(userInfo.ID)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, ResponseList{
+			Response: resp.OK(),
+			APIKeys:  keys,
+		})
+	}
+}
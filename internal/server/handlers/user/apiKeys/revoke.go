@@ -0,0 +1,46 @@
+package apiKeys
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type HandlerRevoke struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerRevoke(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerRevoke {
+	return &HandlerRevoke{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerRevoke) RevokeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "apiKeys.RevokeHandler"
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+		keyGuid := chi.URLParam(r, "id")
+
+		err := h.userService.RevokeAPIKey(userInfo.ID, keyGuid)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("User revoked api key", zap.String("op", op), zap.String("guid", userInfo.ID), zap.String("key", keyGuid))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
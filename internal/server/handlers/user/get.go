@@ -3,20 +3,18 @@ package user
 import (
 	"PetProjectGo/internal/config"
 	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
 	"PetProjectGo/internal/services"
 	"PetProjectGo/pkg/logging"
 	"PetProjectGo/pkg/tokenGen"
+	"errors"
 	"github.com/go-chi/render"
 	"net/http"
-	"strings"
 )
 
-var UnauthorizedError = "unauthorized"
-
 type Response struct {
 	resp.Response
-	Token string                  `json:"new_token,omitempty"`
-	User  *tokenGen.UserInfoToken `json:"user,omitempty"`
+	User *tokenGen.UserInfoToken `json:"user,omitempty"`
 }
 
 type HandlerUserGet struct {
@@ -37,40 +35,21 @@ func NewHandlerUserGet(
 
 func (h *HandlerUserGet) UserGetHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		const bearerPrefix = "Bearer "
-
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" || !strings.HasPrefix(tokenString, bearerPrefix) {
-			render.JSON(w, r, resp.Error(UnauthorizedError))
-			return
-		}
-
-		token := strings.TrimPrefix(tokenString, bearerPrefix)
-		if token == "" {
-			render.JSON(w, r, resp.Error(UnauthorizedError))
+		userInfo, err := h.userService.GetMeInfo(mwAuth.GetUserInfo(r.Context()))
+		if errors.Is(err, services.UserIsUnLogged) {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.ErrorCode(resp.CodeSessionEnded, err.Error()))
 			return
 		}
-
-		newT, userInfo, err := h.userService.GetMeInfo(token)
 		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, resp.Error(err.Error()))
 			return
 		}
 
-		var response Response
-		if newT != "" && newT != token {
-			w.Header().Set("Authorization", "Bearer "+newT)
-			response = Response{
-				Response: resp.OK(),
-				Token:    newT,
-			}
-		} else {
-			response = Response{
-				Response: resp.OK(),
-				User:     userInfo,
-			}
-		}
-
-		render.JSON(w, r, response)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			User:     userInfo,
+		})
 	}
 }
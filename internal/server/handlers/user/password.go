@@ -0,0 +1,92 @@
+package user
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type RequestPassword struct {
+	CurrentPassword   string `json:"current_password" validate:"required"`
+	Password          string `json:"password" validate:"required"`
+	ConfirmedPassword string `json:"confirmed_password" validate:"required,eqfield=Password"`
+}
+
+type ResponsePassword struct {
+	resp.Response
+	RevokedSessions int64 `json:"revoked_sessions"`
+}
+
+type HandlerUserPassword struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerUserPassword(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerUserPassword {
+	return &HandlerUserPassword{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerUserPassword) Validate(req *RequestPassword, login string) []*handlers.ValidationError {
+	errs := handlers.CreateValidationErrorsResp(req)
+
+	violations := h.userService.CheckPassword(req.Password, login)
+	errs = append(errs, handlers.CreatePasswordErrorsResp("RequestPassword.Password", violations)...)
+
+	return errs
+}
+
+func (h *HandlerUserPassword) UserPasswordHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "user.UserPasswordHandler"
+
+		var req RequestPassword
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		errs := h.Validate(&req, userInfo.Login)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		revoked, err := h.userService.ChangePassword(userInfo, req.CurrentPassword, req.Password)
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			render.JSON(w, r, resp.Error(handlers.CreatePasswordErrorsResp("RequestPassword.Password", policyErr.Violations)))
+			return
+		}
+		if errors.Is(err, services.ErrAccountLocked) || errors.Is(err, services.ErrTooManyAttempts) {
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, ResponsePassword{
+			Response:        resp.OK(),
+			RevokedSessions: revoked,
+		})
+	}
+}
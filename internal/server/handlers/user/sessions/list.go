@@ -0,0 +1,48 @@
+package sessions
+
+import (
+	"PetProjectGo/internal/models"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type ResponseSessions struct {
+	resp.Response
+	Sessions []*models.Session `json:"sessions"`
+}
+
+type HandlerSessionsList struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerSessionsList(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerSessionsList {
+	return &HandlerSessionsList{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerSessionsList) SessionsListHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		sessions, err := h.userService.GetSessions(userInfo.ID, userInfo.SessionID)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, ResponseSessions{
+			Response: resp.OK(),
+			Sessions: sessions,
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package sessions
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ResponseRevoke struct {
+	resp.Response
+}
+
+type HandlerSessionRevoke struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerSessionRevoke(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerSessionRevoke {
+	return &HandlerSessionRevoke{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerSessionRevoke) SessionRevokeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "sessions.SessionRevokeHandler"
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+		sessionGuid := chi.URLParam(r, "id")
+
+		err := h.userService.RevokeSession(userInfo.ID, sessionGuid)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("Session revoked", zap.String("op", op), zap.String("guid", userInfo.ID), zap.String("session", sessionGuid))
+
+		render.JSON(w, r, ResponseRevoke{
+			Response: resp.OK(),
+		})
+	}
+}
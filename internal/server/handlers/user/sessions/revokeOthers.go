@@ -0,0 +1,52 @@
+package sessions
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ResponseRevokeOthers struct {
+	resp.Response
+	Revoked int64 `json:"revoked"`
+}
+
+type HandlerSessionsRevokeOthers struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerSessionsRevokeOthers(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerSessionsRevokeOthers {
+	return &HandlerSessionsRevokeOthers{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerSessionsRevokeOthers) SessionsRevokeOthersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "sessions.SessionsRevokeOthersHandler"
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		revoked, err := h.userService.RevokeOtherSessions(userInfo.ID, userInfo.SessionID)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("Other sessions revoked", zap.String("op", op), zap.String("guid", userInfo.ID), zap.Int64("revoked", revoked))
+
+		render.JSON(w, r, ResponseRevokeOthers{
+			Response: resp.OK(),
+			Revoked:  revoked,
+		})
+	}
+}
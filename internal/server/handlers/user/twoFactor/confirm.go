@@ -0,0 +1,74 @@
+package twoFactor
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type RequestConfirm struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type ResponseConfirm struct {
+	resp.Response
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type HandlerConfirm struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerConfirm(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerConfirm {
+	return &HandlerConfirm{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerConfirm) Validate(req *RequestConfirm) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerConfirm) ConfirmHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "twoFactor.ConfirmHandler"
+
+		var req RequestConfirm
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		codes, err := h.userService.ConfirmTwoFactor(userInfo.ID, req.Code)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, ResponseConfirm{
+			Response:      resp.OK(),
+			RecoveryCodes: codes,
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package twoFactor
+
+import (
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type RequestDisable struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type HandlerDisable struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerDisable(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerDisable {
+	return &HandlerDisable{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerDisable) Validate(req *RequestDisable) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerDisable) DisableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "twoFactor.DisableHandler"
+
+		var req RequestDisable
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		err = h.userService.DisableTwoFactor(userInfo.ID, req.Password, req.Code)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,54 @@
+package twoFactor
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ResponseEnroll struct {
+	resp.Response
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type HandlerEnroll struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerEnroll(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerEnroll {
+	return &HandlerEnroll{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerEnroll) EnrollHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "twoFactor.EnrollHandler"
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		secret, uri, err := h.userService.EnrollTwoFactor(userInfo.ID, userInfo.Login)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("Two-factor enrollment started", zap.String("op", op), zap.String("guid", userInfo.ID))
+
+		render.JSON(w, r, ResponseEnroll{
+			Response: resp.OK(),
+			Secret:   secret,
+			URI:      uri,
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/server/handlers"
+	resp "PetProjectGo/internal/server/handlers/response"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// RequestUpdate holds the fields to change; omitted ones are kept.
+type RequestUpdate struct {
+	Name     *string `json:"name,omitempty" validate:"omitempty,max=100"`
+	LastName *string `json:"last_name,omitempty" validate:"omitempty,max=100"`
+}
+
+type ResponseUpdate struct {
+	resp.Response
+	User *models.User `json:"user"`
+}
+
+type HandlerUserUpdate struct {
+	log         *logging.Logger
+	userService *services.UserService
+}
+
+func NewHandlerUserUpdate(
+	log *logging.Logger,
+	userService *services.UserService,
+) *HandlerUserUpdate {
+	return &HandlerUserUpdate{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func (h *HandlerUserUpdate) Validate(req *RequestUpdate) []*handlers.ValidationError {
+	return handlers.CreateValidationErrorsResp(req)
+}
+
+func (h *HandlerUserUpdate) UserUpdateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "user.UserUpdateHandler"
+
+		var req RequestUpdate
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			h.log.Error("Failed to parse request body", zap.String("op", op), zap.Error(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		errs := h.Validate(&req)
+		if len(errs) != 0 {
+			render.JSON(w, r, resp.Error(errs))
+			return
+		}
+
+		userInfo := mwAuth.GetUserInfo(r.Context())
+
+		user, err := h.userService.UpdateProfile(userInfo.ID, req.Name, req.LastName)
+		if err != nil {
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		h.log.Info("User updated profile", zap.String("op", op), zap.String("guid", userInfo.ID))
+
+		render.JSON(w, r, ResponseUpdate{
+			Response: resp.OK(),
+			User:     user,
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package handlers
 
-import "github.com/go-playground/validator/v10"
+import (
+	"PetProjectGo/pkg/passwordPolicy"
+	"github.com/go-playground/validator/v10"
+)
 
 type ValidationError struct {
 	Field string `json:"field"`
@@ -23,3 +26,18 @@ func CreateValidationErrorsResp(req interface{}) []*ValidationError {
 	}
 	return errs
 }
+
+// CreatePasswordErrorsResp reports the broken password rules in the same
+// format as CreateValidationErrorsResp, with the rule as the tag.
+func CreatePasswordErrorsResp(field string, violations []passwordPolicy.Violation) []*ValidationError {
+	var errs []*ValidationError
+
+	for _, violation := range violations {
+		errs = append(errs, &ValidationError{
+			Field: field,
+			Tag:   violation.Rule,
+			Value: violation.Param,
+		})
+	}
+	return errs
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	resp "PetProjectGo/internal/server/handlers/response"
+	"PetProjectGo/internal/services"
+	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/tokenGen"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+const bearerPrefix = "Bearer "
+
+var UnauthorizedError = "unauthorized"
+var ForbiddenError = "forbidden"
+
+type ctxKey int
+
+const (
+	userInfoKey ctxKey = iota
+	tokenKey
+)
+
+func NewAuthMw(logger *logging.Logger, userService *services.UserService) func(next http.Handler) http.Handler {
+	logger.Info("auth middleware initialized", zap.String("component", "middleware/auth"))
+	return func(next http.Handler) http.Handler {
+		log := logger.With(zap.String("component", "middleware/auth"))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			token, ok := GetBearerToken(r)
+			if !ok {
+				unauthorized(w, r, resp.CodeTokenMissing)
+				return
+			}
+
+			userInfo, err := userService.Authenticate(token)
+			if err != nil {
+				log.Debug(
+					"token rejected",
+					zap.String("path", r.URL.Path),
+					zap.String("request_id", middleware.GetReqID(r.Context())),
+					zap.Error(err),
+				)
+				unauthorized(w, r, tokenErrorCode(err))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userInfoKey, userInfo)
+			ctx = context.WithValue(ctx, tokenKey, token)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireRoles lets the request through only if the user put into the context
+// by NewAuthMw has one of the given roles. It must be used after NewAuthMw.
+func RequireRoles(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			userInfo := GetUserInfo(r.Context())
+			if userInfo == nil {
+				unauthorized(w, r, resp.CodeTokenMissing)
+				return
+			}
+
+			for _, role := range roles {
+				if userInfo.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(ForbiddenError))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireScope lets requests made with an API key through only if the key
+// has the scope. Requests made with an access token are not limited. It must
+// be used after NewAuthMw.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			userInfo := GetUserInfo(r.Context())
+			if userInfo == nil {
+				unauthorized(w, r, resp.CodeTokenMissing)
+				return
+			}
+
+			if userInfo.APIKeyID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, s := range userInfo.Scopes {
+				if s == scope {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.ErrorCode(resp.CodeInsufficientScope, ForbiddenError))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireSession refuses requests made with an API key, for routes that
+// manage the account or its credentials. It must be used after NewAuthMw.
+func RequireSession(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		userInfo := GetUserInfo(r.Context())
+		if userInfo == nil {
+			unauthorized(w, r, resp.CodeTokenMissing)
+			return
+		}
+
+		if userInfo.APIKeyID != "" {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.ErrorCode(resp.CodeSessionRequired, ForbiddenError))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// GetBearerToken extracts the token from the "Authorization: Bearer <token>" header.
+func GetBearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix))
+	if token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+// GetUserInfo returns the verified token owner put into the context by NewAuthMw.
+func GetUserInfo(ctx context.Context) *tokenGen.UserInfoToken {
+	userInfo, _ := ctx.Value(userInfoKey).(*tokenGen.UserInfoToken)
+	return userInfo
+}
+
+// GetToken returns the raw access token put into the context by NewAuthMw.
+func GetToken(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey).(string)
+	return token
+}
+
+// tokenErrorCode tells an expired access token, which can be refreshed,
+// from one that has to be replaced by logging in again.
+func tokenErrorCode(err error) string {
+	switch {
+	case errors.Is(err, tokenGen.ErrTokenExpired):
+		return resp.CodeTokenExpired
+	case errors.Is(err, services.ErrTokenRevoked):
+		return resp.CodeTokenRevoked
+	case errors.Is(err, services.ErrAPIKeyExpired):
+		return resp.CodeAPIKeyExpired
+	default:
+		return resp.CodeTokenInvalid
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, code string) {
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.ErrorCode(code, UnauthorizedError))
+}
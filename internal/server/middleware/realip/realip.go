@@ -0,0 +1,78 @@
+package realip
+
+import (
+	"PetProjectGo/pkg/logging"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// NewRealIPMw sets the remote address of a request coming from a trusted
+// proxy to the client IP it forwarded. X-Forwarded-For is read from the
+// right, the end the proxies append to, and the first address that is not a
+// trusted proxy is the client; the addresses left of it may be forged by the
+// client. A request from any other peer keeps its remote address.
+func NewRealIPMw(logger *logging.Logger, trusted []*net.IPNet) func(next http.Handler) http.Handler {
+	logger.Info("real IP middleware initialized", zap.String("component", "middleware/realip"), zap.Int("trusted_proxies", len(trusted)))
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if len(trusted) != 0 && isTrusted(peerIP(r), trusted) {
+				ip := forwardedIP(r.Header.Values("X-Forwarded-For"), trusted)
+				if ip != "" {
+					r.RemoteAddr = ip
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func peerIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// forwardedIP returns the rightmost address of the X-Forwarded-For headers
+// that is not a trusted proxy, the leftmost one if all of them are, or ""
+// when an address is malformed or there is none.
+func forwardedIP(headers []string, trusted []*net.IPNet) string {
+	var hops []string
+	for _, header := range headers {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	var ip net.IP
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			return ""
+		}
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
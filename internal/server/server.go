@@ -2,8 +2,13 @@ package server
 
 import (
 	"PetProjectGo/internal/config"
+	"PetProjectGo/internal/models"
 	"PetProjectGo/internal/server/handlers"
+	"PetProjectGo/internal/server/handlers/admin"
+	"PetProjectGo/internal/server/handlers/auth/email"
+	"PetProjectGo/internal/server/handlers/auth/jwks"
 	"PetProjectGo/internal/server/handlers/auth/login"
+	"PetProjectGo/internal/server/handlers/auth/password"
 	"PetProjectGo/internal/server/handlers/auth/refresh"
 	"PetProjectGo/internal/server/handlers/auth/register"
 	"PetProjectGo/internal/server/handlers/auth/unlogin"
@@ -11,45 +16,95 @@ import (
 	"PetProjectGo/internal/server/handlers/market/product"
 	"PetProjectGo/internal/server/handlers/market/product/productFilter"
 	userGroup "PetProjectGo/internal/server/handlers/user"
+	"PetProjectGo/internal/server/handlers/user/account"
+	"PetProjectGo/internal/server/handlers/user/apiKeys"
+	"PetProjectGo/internal/server/handlers/user/sessions"
+	"PetProjectGo/internal/server/handlers/user/twoFactor"
+	mwAuth "PetProjectGo/internal/server/middleware/auth"
 	mwLogger "PetProjectGo/internal/server/middleware/logger"
+	mwRealIP "PetProjectGo/internal/server/middleware/realip"
 	"PetProjectGo/internal/services"
 	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/mail"
 	"PetProjectGo/pkg/storage/mongodb"
+	"PetProjectGo/pkg/tokenGen"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 )
 
 type Server struct {
-	log      *logging.Logger
-	cfg      *config.Config
-	router   *chi.Mux
-	mongo    *mongodb.MongoDB
-	postgres *sqlx.DB
-	index    *handlers.HandlerIndex
-	auth     *GroupServerAuth
-	user     *GroupServerUser
-	market   *GroupServerMarket
+	log       *logging.Logger
+	cfg       *config.Config
+	router    *chi.Mux
+	wellKnown *chi.Mux
+	mongo     *mongodb.MongoDB
+	postgres  *sqlx.DB
+	index     *handlers.HandlerIndex
+	auth      *GroupServerAuth
+	user      *GroupServerUser
+	market    *GroupServerMarket
+	admin     *GroupServerAdmin
+	authMw    func(next http.Handler) http.Handler
 }
 
 type GroupServerAuth struct {
-	register *register.HandlerRegister
-	login    *login.HandlerLogin
-	unlogin  *unlogin.HandlerUnLogin
-	refresh  *refresh.HandlerRefresh
+	register       *register.HandlerRegister
+	login          *login.HandlerLogin
+	loginTwoFactor *login.HandlerLoginTwoFactor
+	unlogin        *unlogin.HandlerUnLogin
+	refresh        *refresh.HandlerRefresh
+	jwks           *jwks.HandlerJWKS
+	resetRequest   *password.HandlerResetRequest
+	resetConfirm   *password.HandlerResetConfirm
+	emailConfirm   *email.HandlerConfirm
+	emailResend    *email.HandlerResend
 }
 
 type GroupServerUser struct {
-	userInfo *userGroup.HandlerUserGet
+	userInfo             *userGroup.HandlerUserGet
+	userUpdate           *userGroup.HandlerUserUpdate
+	userPassword         *userGroup.HandlerUserPassword
+	sessionsList         *sessions.HandlerSessionsList
+	sessionRevoke        *sessions.HandlerSessionRevoke
+	sessionsRevokeOthers *sessions.HandlerSessionsRevokeOthers
+	twoFactorEnroll      *twoFactor.HandlerEnroll
+	twoFactorConfirm     *twoFactor.HandlerConfirm
+	twoFactorDisable     *twoFactor.HandlerDisable
+	accountDelete        *account.HandlerDelete
+	accountCancelDelete  *account.HandlerCancelDelete
+	accountExport        *account.HandlerExport
+	apiKeysList          *apiKeys.HandlerList
+	apiKeyCreate         *apiKeys.HandlerCreate
+	apiKeyRevoke         *apiKeys.HandlerRevoke
+}
+
+type GroupServerAdmin struct {
+	userRole         *admin.HandlerUserRole
+	userLogout       *admin.HandlerUserLogout
+	userUnlock       *admin.HandlerUserUnlock
+	userAPIKeyAdd    *admin.HandlerUserAPIKeyAdd
+	userAPIKeyRevoke *admin.HandlerUserAPIKeyRevoke
 }
 
 type GroupServerMarket struct {
-	category             *category.HandlerCategoryAdd
-	categoryAll          *category.HandlerCategoryAll
-	product              *product.HandlerProductAdd
-	productAllByCategory *productFilter.HandlerProductGetByCompanyGuid
+	category       *category.HandlerCategoryAdd
+	categoryAll    *category.HandlerCategoryAll
+	categoryGet    *category.HandlerCategoryGet
+	categoryRename *category.HandlerCategoryRename
+	categoryDelete *category.HandlerCategoryDelete
+	categoryTree   *category.HandlerCategoryTree
+	categoryPath   *category.HandlerCategoryPath
+	categoryMove   *category.HandlerCategoryMove
+	product        *product.HandlerProductAdd
+	productGet     *product.HandlerProductGet
+	productUpdate  *product.HandlerProductUpdate
+	productDelete  *product.HandlerProductDelete
+	productList    *productFilter.HandlerProductList
+	productSearch  *productFilter.HandlerProductSearch
 }
 
 func NewWebServer(
@@ -58,7 +113,35 @@ func NewWebServer(
 	mongo *mongodb.MongoDB,
 	postgres *sqlx.DB,
 ) (*Server, error) {
-	userService := services.NewUserService(log, &cfg.App, mongo, postgres)
+	keyService, err := services.NewKeyService(log, &cfg.App, mongo)
+	if err != nil {
+		return nil, err
+	}
+	go keyService.RunRotation()
+
+	tokens := tokenGen.NewGenerator(keyService.KeySet(), tokenGen.Options{
+		Issuer:   cfg.App.JWT.Issuer,
+		Audience: cfg.App.JWT.Audience,
+		Leeway:   cfg.App.JWT.Leeway,
+	})
+
+	mailer, err := mail.NewSender(&cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	userService, err := services.NewUserService(log, &cfg.App, mongo, postgres, tokens, mailer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = userService.BootstrapAdmin()
+	if err != nil {
+		log.Error("Error bootstrapping admin", zap.Error(err))
+	}
+	go userService.RunAccountDeletion()
+	go userService.RunRegistrationOutbox()
+
 	marketCService, err := services.NewMarketCategoryService(mongo, userService)
 	if err != nil {
 		return nil, err
@@ -69,13 +152,16 @@ func NewWebServer(
 		return nil, err
 	}
 	return &Server{
-		log:    log,
-		cfg:    cfg,
-		router: chi.NewRouter(),
-		index:  handlers.NewHandlerIndex(log, userService, marketCService, marketPService),
-		auth:   NewGroupAuth(cfg, log, userService),
-		user:   NewGroupUser(log, userService),
-		market: NewGroupMarket(log, marketCService, marketPService),
+		log:       log,
+		cfg:       cfg,
+		router:    chi.NewRouter(),
+		wellKnown: chi.NewRouter(),
+		index:     handlers.NewHandlerIndex(log, userService, marketCService, marketPService),
+		auth:      NewGroupAuth(cfg, log, userService, keyService),
+		user:      NewGroupUser(log, userService),
+		market:    NewGroupMarket(log, marketCService, marketPService),
+		admin:     NewGroupAdmin(log, userService),
+		authMw:    mwAuth.NewAuthMw(log, userService),
 	}, nil
 }
 
@@ -83,12 +169,19 @@ func NewGroupAuth(
 	cfg *config.Config,
 	log *logging.Logger,
 	userService *services.UserService,
+	keyService *services.KeyService,
 ) *GroupServerAuth {
 	return &GroupServerAuth{
-		register: register.NewHandlerRegister(&cfg.App, log, userService),
-		login:    login.NewHandlerLogin(log, userService),
-		unlogin:  unlogin.NewHandlerUnLogin(log, userService),
-		refresh:  refresh.NewHandlerRefresh(log, userService),
+		register:       register.NewHandlerRegister(&cfg.App, log, userService),
+		login:          login.NewHandlerLogin(log, userService),
+		loginTwoFactor: login.NewHandlerLoginTwoFactor(log, userService),
+		unlogin:        unlogin.NewHandlerUnLogin(log, userService),
+		refresh:        refresh.NewHandlerRefresh(log, userService),
+		jwks:           jwks.NewHandlerJWKS(log, keyService),
+		resetRequest:   password.NewHandlerResetRequest(log, userService),
+		resetConfirm:   password.NewHandlerResetConfirm(log, userService),
+		emailConfirm:   email.NewHandlerConfirm(log, userService),
+		emailResend:    email.NewHandlerResend(log, userService),
 	}
 }
 
@@ -97,7 +190,21 @@ func NewGroupUser(
 	userService *services.UserService,
 ) *GroupServerUser {
 	return &GroupServerUser{
-		userInfo: userGroup.NewHandlerUserGet(log, userService),
+		userInfo:             userGroup.NewHandlerUserGet(log, userService),
+		userUpdate:           userGroup.NewHandlerUserUpdate(log, userService),
+		userPassword:         userGroup.NewHandlerUserPassword(log, userService),
+		sessionsList:         sessions.NewHandlerSessionsList(log, userService),
+		sessionRevoke:        sessions.NewHandlerSessionRevoke(log, userService),
+		sessionsRevokeOthers: sessions.NewHandlerSessionsRevokeOthers(log, userService),
+		twoFactorEnroll:      twoFactor.NewHandlerEnroll(log, userService),
+		twoFactorConfirm:     twoFactor.NewHandlerConfirm(log, userService),
+		twoFactorDisable:     twoFactor.NewHandlerDisable(log, userService),
+		accountDelete:        account.NewHandlerDelete(log, userService),
+		accountCancelDelete:  account.NewHandlerCancelDelete(log, userService),
+		accountExport:        account.NewHandlerExport(log, userService),
+		apiKeysList:          apiKeys.NewHandlerList(log, userService),
+		apiKeyCreate:         apiKeys.NewHandlerCreate(log, userService),
+		apiKeyRevoke:         apiKeys.NewHandlerRevoke(log, userService),
 	}
 }
 
@@ -107,10 +214,33 @@ func NewGroupMarket(
 	productService *services.MarketProductService,
 ) *GroupServerMarket {
 	return &GroupServerMarket{
-		category:             category.NewHandlerCategoryAdd(log, categoryService),
-		categoryAll:          category.NewHandlerCategoryAll(log, categoryService),
-		product:              product.NewHandlerProductAdd(log, productService),
-		productAllByCategory: productFilter.NewHandlerProductGetByCompanyGuid(log, productService),
+		category:       category.NewHandlerCategoryAdd(log, categoryService),
+		categoryAll:    category.NewHandlerCategoryAll(log, categoryService),
+		categoryGet:    category.NewHandlerCategoryGet(log, categoryService),
+		categoryRename: category.NewHandlerCategoryRename(log, categoryService),
+		categoryDelete: category.NewHandlerCategoryDelete(log, categoryService),
+		categoryTree:   category.NewHandlerCategoryTree(log, categoryService),
+		categoryPath:   category.NewHandlerCategoryPath(log, categoryService),
+		categoryMove:   category.NewHandlerCategoryMove(log, categoryService),
+		product:        product.NewHandlerProductAdd(log, productService),
+		productGet:     product.NewHandlerProductGet(log, productService),
+		productUpdate:  product.NewHandlerProductUpdate(log, productService),
+		productDelete:  product.NewHandlerProductDelete(log, productService),
+		productList:    productFilter.NewHandlerProductList(log, productService),
+		productSearch:  productFilter.NewHandlerProductSearch(log, productService),
+	}
+}
+
+func NewGroupAdmin(
+	log *logging.Logger,
+	userService *services.UserService,
+) *GroupServerAdmin {
+	return &GroupServerAdmin{
+		userRole:         admin.NewHandlerUserRole(log, userService),
+		userLogout:       admin.NewHandlerUserLogout(log, userService),
+		userUnlock:       admin.NewHandlerUserUnlock(log, userService),
+		userAPIKeyAdd:    admin.NewHandlerUserAPIKeyAdd(log, userService),
+		userAPIKeyRevoke: admin.NewHandlerUserAPIKeyRevoke(log, userService),
 	}
 }
 
@@ -119,7 +249,7 @@ func (s *Server) Run() {
 
 	srv := &http.Server{
 		Addr:         s.cfg.Web.Address,
-		Handler:      s.router,
+		Handler:      s.handler(),
 		ReadTimeout:  s.cfg.Web.Timeout,
 		WriteTimeout: s.cfg.Web.Timeout,
 		IdleTimeout:  s.cfg.Web.IdleTimeout,
@@ -136,10 +266,32 @@ func (s *Server) Run() {
 
 func (s *Server) registerMiddlewares() {
 	s.log.Info("Registering middlewares")
-	s.router.Use(middleware.RequestID)
-	s.router.Use(mwLogger.NewLoggerMw(s.log))
-	s.router.Use(middleware.Recoverer)
+	trustedProxies, err := s.cfg.Web.TrustedProxyNets()
+	if err != nil {
+		s.log.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+
+	middlewares := chi.Middlewares{
+		middleware.RequestID,
+		mwRealIP.NewRealIPMw(s.log, trustedProxies),
+		mwLogger.NewLoggerMw(s.log),
+		middleware.Recoverer,
+	}
+	s.router.Use(middlewares...)
 	s.router.Use(middleware.URLFormat)
+	s.wellKnown.Use(middlewares...)
+}
+
+// handler routes the well-known paths, whose names keep their extension, to
+// their own router, out of the reach of middleware.URLFormat.
+func (s *Server) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/.well-known/") {
+			s.wellKnown.ServeHTTP(w, r)
+			return
+		}
+		s.router.ServeHTTP(w, r)
+	})
 }
 
 func (s *Server) registerRouters() {
@@ -148,28 +300,93 @@ func (s *Server) registerRouters() {
 	s.log.Info("Registering main path")
 	s.router.Get("/", s.index.IndexHandler())
 
+	s.wellKnown.Get("/.well-known/jwks.json", s.auth.jwks.JWKSHandler())
+
 	s.log.Info("Registering auth group")
 	s.router.Route("/auth", func(r chi.Router) {
 		r.Post("/register", s.auth.register.RegisterHandler())
 		r.Post("/login", s.auth.login.LoginHandler())
-		r.Post("/unlogin", s.auth.unlogin.UnLoginHandler())
+		r.Post("/login/2fa", s.auth.loginTwoFactor.LoginTwoFactorHandler())
+		r.With(s.authMw, mwAuth.RequireSession).Post("/unlogin", s.auth.unlogin.UnLoginHandler())
 		r.Post("/refresh", s.auth.refresh.RefreshHandler())
+		r.Post("/password/reset/request", s.auth.resetRequest.ResetRequestHandler())
+		r.Post("/password/reset/confirm", s.auth.resetConfirm.ResetConfirmHandler())
+		r.Get("/email/confirm", s.auth.emailConfirm.ConfirmHandler())
+		r.Post("/email/confirm", s.auth.emailConfirm.ConfirmHandler())
+		r.Post("/email/resend", s.auth.emailResend.ResendHandler())
 	})
 
 	s.log.Info("Registering user group")
 	s.router.Route("/user", func(r chi.Router) {
-		r.Get("/me", s.user.userInfo.UserGetHandler())
+		r.Use(s.authMw)
+		r.With(mwAuth.RequireScope(models.ScopeUser)).Get("/me", s.user.userInfo.UserGetHandler())
+
+		// The account and its credentials are managed with a session only.
+		r.Group(func(r chi.Router) {
+			r.Use(mwAuth.RequireSession)
+			r.Patch("/me", s.user.userUpdate.UserUpdateHandler())
+			r.Post("/me/password", s.user.userPassword.UserPasswordHandler())
+			r.Post("/me/delete", s.user.accountDelete.DeleteHandler())
+			r.Delete("/me/delete", s.user.accountCancelDelete.CancelDeleteHandler())
+			r.Get("/me/export", s.user.accountExport.ExportHandler())
+			r.Get("/sessions", s.user.sessionsList.SessionsListHandler())
+			r.Delete("/sessions", s.user.sessionsRevokeOthers.SessionsRevokeOthersHandler())
+			r.Delete("/sessions/{id}", s.user.sessionRevoke.SessionRevokeHandler())
+			r.Post("/2fa/enroll", s.user.twoFactorEnroll.EnrollHandler())
+			r.Post("/2fa/confirm", s.user.twoFactorConfirm.ConfirmHandler())
+			r.Post("/2fa/disable", s.user.twoFactorDisable.DisableHandler())
+			r.Get("/api-keys", s.user.apiKeysList.ListHandler())
+			r.Post("/api-keys", s.user.apiKeyCreate.CreateHandler())
+			r.Delete("/api-keys/{id}", s.user.apiKeyRevoke.RevokeHandler())
+		})
 	})
 
 	s.log.Info("Registering category group")
 	s.router.Route("/category", func(r chi.Router) {
-		r.Post("/add", s.market.category.AddCategoryHandler())
 		r.Get("/all", s.market.categoryAll.AllCategoriesHandler())
+		r.Get("/tree", s.market.categoryTree.CategoryTreeHandler())
+		r.Get("/{id}", s.market.categoryGet.GetCategoryHandler())
+		r.Get("/{id}/path", s.market.categoryPath.CategoryPathHandler())
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.authMw)
+			r.Use(mwAuth.RequireScope(models.ScopeCategory))
+			r.Use(mwAuth.RequireRoles(models.RoleAdmin, models.RoleSeller))
+			r.Post("/add", s.market.category.AddCategoryHandler())
+			r.Patch("/{id}", s.market.categoryRename.RenameCategoryHandler())
+			r.Delete("/{id}", s.market.categoryDelete.DeleteCategoryHandler())
+			r.Post("/{id}/move", s.market.categoryMove.MoveCategoryHandler())
+		})
 	})
 
 	s.log.Info("Registering product group")
 	s.router.Route("/product", func(r chi.Router) {
-		r.Post("/add", s.market.product.AddProductHandler())
-		r.Get("/all", s.market.productAllByCategory.AddProductGetByCompanyGuidHandler())
+		r.Get("/", s.market.productList.ListProductsHandler())
+		r.Get("/all", s.market.productList.ListProductsHandler())
+		r.Get("/search", s.market.productSearch.SearchProductsHandler())
+		r.Get("/{id}", s.market.productGet.GetProductHandler())
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.authMw)
+			r.Use(mwAuth.RequireScope(models.ScopeProduct))
+			r.Use(mwAuth.RequireRoles(models.RoleAdmin, models.RoleSeller))
+			r.Post("/add", s.market.product.AddProductHandler())
+			r.Patch("/{id}", s.market.productUpdate.UpdateProductHandler())
+			r.Delete("/{id}", s.market.productDelete.DeleteProductHandler())
+		})
+	})
+
+	s.log.Info("Registering admin group")
+	s.router.Route("/admin", func(r chi.Router) {
+		r.Use(s.authMw)
+		r.Use(mwAuth.RequireScope(models.ScopeAdmin))
+		r.Use(mwAuth.RequireRoles(models.RoleAdmin))
+		r.Patch("/users/{id}/role", s.admin.userRole.UserRoleHandler())
+		r.Post("/users/{id}/logout", s.admin.userLogout.UserLogoutHandler())
+		r.Post("/users/{id}/unlock", s.admin.userUnlock.UserUnlockHandler())
+		r.With(mwAuth.RequireSession).Post("/users/{id}/api-keys", s.admin.userAPIKeyAdd.UserAPIKeyAddHandler())
+		r.With(mwAuth.RequireSession).Delete(
+			"/users/{id}/api-keys/{keyId}", s.admin.userAPIKeyRevoke.UserAPIKeyRevokeHandler(),
+		)
 	})
 }
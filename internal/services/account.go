@@ -0,0 +1,242 @@
+package services
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/postgresRepo"
+	"PetProjectGo/pkg/tokenGen"
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+var ErrDeletionNotScheduled = fmt.Errorf("account deletion is not scheduled")
+
+// UserDataExport is everything kept about a user. Secrets such as password
+// hashes, 2FA secrets and token hashes are left out.
+type UserDataExport struct {
+	ExportedAt      time.Time                 `json:"exported_at"`
+	User            *models.User              `json:"user"`
+	Sessions        []*models.Session         `json:"sessions"`
+	Credentials     *postgresRepo.Credentials `json:"credentials"`
+	PasswordChanges []time.Time               `json:"password_changes"`
+	TwoFactor       TwoFactorExport           `json:"two_factor"`
+	Tokens          []UserTokenExport         `json:"tokens"`
+	APIKeys         []*postgresRepo.APIKey    `json:"api_keys"`
+	FailedLogins    *FailedLoginsExport       `json:"failed_logins,omitempty"`
+}
+
+type TwoFactorExport struct {
+	Enabled       bool       `json:"enabled"`
+	EnabledAt     *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodes int        `json:"unused_recovery_codes"`
+}
+
+type UserTokenExport struct {
+	Purpose   string     `json:"purpose"`
+	CreatedAt *time.Time `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+type FailedLoginsExport struct {
+	Count        int        `json:"count"`
+	LastFailedAt *time.Time `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+// RequestAccountDeletion schedules the deletion of the logged-in user after
+// the grace period. Until then the user may still log in and cancel it.
+func (u *UserService) RequestAccountDeletion(userInfo *tokenGen.UserInfoToken, password string) (*time.Time, error) {
+	const op = "UserService.RequestAccountDeletion"
+
+	err := u.reauthenticate(userInfo, password)
+	if err != nil {
+		return nil, err
+	}
+
+	timeNow := time.Now()
+	deleteAt := timeNow.Add(u.cfg.AccountDeletionGracePeriod)
+
+	err = u.mongo.ScheduleDeletion(userInfo.ID, &deleteAt, &timeNow)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Account deletion scheduled", zap.String("op", op), zap.String("guid", userInfo.ID), zap.Time("at", deleteAt))
+
+	return &deleteAt, nil
+}
+
+func (u *UserService) CancelAccountDeletion(userGuid string) error {
+	const op = "UserService.CancelAccountDeletion"
+
+	user, err := u.mongo.GetByGuid(userGuid)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+
+	timeNow := time.Now()
+	err = u.mongo.ScheduleDeletion(userGuid, nil, &timeNow)
+	if err != nil {
+		return err
+	}
+
+	u.log.Info("Account deletion cancelled", zap.String("op", op), zap.String("guid", userGuid))
+
+	return nil
+}
+
+// DeleteDueAccounts deletes the users whose grace period is over and returns
+// how many were deleted.
+func (u *UserService) DeleteDueAccounts() (int, error) {
+	const op = "UserService.DeleteDueAccounts"
+
+	timeNow := time.Now()
+	users, err := u.mongo.GetDeletionDue(&timeNow)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, user := range users {
+		err = u.deleteAccount(user)
+		if err != nil {
+			u.log.Error("Error deleting account", zap.String("op", op), zap.String("guid", user.GUID), zap.Error(err))
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// RunAccountDeletion deletes due accounts every
+// app.account_deletion_check_interval. It blocks, so run it in a goroutine.
+func (u *UserService) RunAccountDeletion() {
+	const op = "UserService.RunAccountDeletion"
+
+	ticker := time.NewTicker(u.cfg.AccountDeletionCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := u.DeleteDueAccounts()
+		if err != nil {
+			u.log.Error("Error deleting due accounts", zap.String("op", op), zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			u.log.Info("Accounts deleted", zap.String("op", op), zap.Int("count", deleted))
+		}
+	}
+}
+
+// deleteAccount ends the sessions of the user and removes the user from
+// Postgres and then Mongo. The Mongo document goes last so a failed attempt
+// is retried on the next run.
+func (u *UserService) deleteAccount(user *models.User) error {
+	const op = "UserService.deleteAccount"
+
+	_, err := u.ForceLogout(user.GUID)
+	if err != nil {
+		return err
+	}
+
+	err = u.postgres.DeleteUser(user.GUID, user.Login)
+	if err != nil {
+		return err
+	}
+
+	err = u.mongo.DeleteByGuid(user.GUID)
+	if err != nil {
+		return err
+	}
+
+	u.log.Info("Account deleted", zap.String("op", op), zap.String("guid", user.GUID))
+
+	return nil
+}
+
+// ExportUserData collects everything kept about the user.
+func (u *UserService) ExportUserData(userGuid string) (*UserDataExport, error) {
+	user, err := u.mongo.GetByGuid(userGuid)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := u.GetSessions(userGuid, "")
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := u.postgres.GetCredentials(userGuid)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordChanges, err := u.postgres.GetPasswordChanges(userGuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var twoFactor TwoFactorExport
+	secret, err := u.twoFactor.GetTOTP(userGuid)
+	if err != nil && !errors.Is(err, postgresRepo.ErrTOTPNotFound) {
+		return nil, err
+	}
+	if secret != nil && secret.EnabledAt != nil {
+		twoFactor.Enabled = true
+		twoFactor.EnabledAt = secret.EnabledAt
+		twoFactor.RecoveryCodes, err = u.twoFactor.CountRecoveryCodes(userGuid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	userTokens, err := u.userTokens.GetByUser(userGuid)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]UserTokenExport, 0, len(userTokens))
+	for _, token := range userTokens {
+		tokens = append(tokens, UserTokenExport{
+			Purpose:   token.Purpose,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    token.UsedAt,
+		})
+	}
+
+	apiKeys, err := u.apiKeys.GetByUser(userGuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var failedLogins *FailedLoginsExport
+	attempt, err := u.loginAttempts.Get(postgresRepo.LoginAttemptKeyLogin, user.Login)
+	if err != nil && !errors.Is(err, postgresRepo.ErrLoginAttemptNotFound) {
+		return nil, err
+	}
+	if attempt != nil {
+		failedLogins = &FailedLoginsExport{
+			Count:        attempt.FailedCount,
+			LastFailedAt: attempt.LastFailedAt,
+			LockedUntil:  attempt.LockedUntil,
+		}
+	}
+
+	return &UserDataExport{
+		ExportedAt:      time.Now(),
+		User:            user,
+		Sessions:        sessions,
+		Credentials:     credentials,
+		PasswordChanges: passwordChanges,
+		TwoFactor:       twoFactor,
+		Tokens:          tokens,
+		APIKeys:         apiKeys,
+		FailedLogins:    failedLogins,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/mongoRepo"
+	"PetProjectGo/internal/repository/postgresRepo"
+	"PetProjectGo/pkg/tokenGen"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// An API key looks like "pgo_<prefix>_<secret>". The prefix identifies the
+// key and may be shown; the secret is only returned when the key is created.
+const (
+	apiKeyMarker       = "pgo_"
+	apiKeyPrefixBytes  = 6
+	apiKeySecretBytes  = 32
+	apiKeyPrefixLength = apiKeyPrefixBytes * 2
+)
+
+var InvalidAPIKey = fmt.Errorf("invalid api key")
+var ErrAPIKeyExpired = fmt.Errorf("api key expired")
+var ErrAPIKeyNotFound = fmt.Errorf("api key not found")
+var ErrAPIKeyLimit = fmt.Errorf("too many api keys")
+var ErrInvalidScope = fmt.Errorf("invalid scope")
+var ErrInvalidAPIKeyTTL = fmt.Errorf("invalid api key lifetime")
+
+// NewAPIKey is a created key together with its secret, which cannot be
+// retrieved again.
+type NewAPIKey struct {
+	Key    string               `json:"key"`
+	APIKey *postgresRepo.APIKey `json:"api_key"`
+}
+
+// IsAPIKey tells API keys from access tokens in the Authorization header.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyMarker)
+}
+
+// CreateAPIKey creates a key for the user limited to the given scopes. A ttl
+// of 0 means app.api_keys.default_ttl. The admin scope is only given to admins.
+func (u *UserService) CreateAPIKey(userGuid string, name string, scopes []string, ttl time.Duration) (*NewAPIKey, error) {
+	const op = "UserService.CreateAPIKey"
+
+	user, err := u.mongo.GetByGuid(userGuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return nil, ErrInvalidScope
+		}
+		if scope == models.ScopeAdmin && user.Role != models.RoleAdmin {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	if ttl == 0 {
+		ttl = u.cfg.APIKeys.DefaultTTL
+	}
+	if ttl < 0 || ttl > u.cfg.APIKeys.MaxTTL {
+		return nil, ErrInvalidAPIKeyTTL
+	}
+
+	timeNow := time.Now()
+	count, err := u.apiKeys.CountActive(userGuid, &timeNow)
+	if err != nil {
+		return nil, err
+	}
+	if count >= u.cfg.APIKeys.MaxPerUser {
+		return nil, ErrAPIKeyLimit
+	}
+
+	prefix, err := randomHex(apiKeyPrefixBytes)
+	if err != nil {
+		return nil, err
+	}
+	secretBytes := make([]byte, apiKeySecretBytes)
+	_, err = rand.Read(secretBytes)
+	if err != nil {
+		return nil, err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	expiresAt := timeNow.Add(ttl)
+	key := &postgresRepo.APIKey{
+		GUID:       uuid.New().String(),
+		UserGUID:   userGuid,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: tokenGen.HashToken(secret),
+		Scopes:     scopes,
+		ExpiresAt:  &expiresAt,
+		CreatedAt:  &timeNow,
+	}
+	err = u.apiKeys.AddKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Api key created", zap.String("op", op), zap.String("guid", userGuid), zap.String("prefix", prefix))
+
+	return &NewAPIKey{
+		Key:    apiKeyMarker + prefix + "_" + secret,
+		APIKey: key,
+	}, nil
+}
+
+func (u *UserService) GetAPIKeys(userGuid string) ([]*postgresRepo.APIKey, error) {
+	return u.apiKeys.GetByUser(userGuid)
+}
+
+func (u *UserService) RevokeAPIKey(userGuid string, keyGuid string) error {
+	timeNow := time.Now()
+	err := u.apiKeys.Revoke(userGuid, keyGuid, &timeNow)
+	if errors.Is(err, postgresRepo.ErrAPIKeyNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// authenticateAPIKey checks an API key and returns its owner limited to the
+// scopes of the key. The role is the current one of the owner.
+func (u *UserService) authenticateAPIKey(token string) (*tokenGen.UserInfoToken, error) {
+	const op = "UserService.authenticateAPIKey"
+
+	rest := strings.TrimPrefix(token, apiKeyMarker)
+	if len(rest) <= apiKeyPrefixLength+1 || rest[apiKeyPrefixLength] != '_' {
+		return nil, InvalidAPIKey
+	}
+	prefix, secret := rest[:apiKeyPrefixLength], rest[apiKeyPrefixLength+1:]
+
+	key, err := u.apiKeys.GetByPrefix(prefix)
+	if errors.Is(err, postgresRepo.ErrAPIKeyNotFound) {
+		return nil, InvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(tokenGen.HashToken(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, InvalidAPIKey
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+	timeNow := time.Now()
+	if !key.ExpiresAt.After(timeNow) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	user, err := u.mongo.GetByGuid(key.UserGUID)
+	if errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return nil, InvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.apiKeys.TouchLastUsed(key.GUID, &timeNow, u.cfg.APIKeys.LastUsedInterval)
+	if err != nil {
+		u.log.Error("Error recording api key use", zap.String("op", op), zap.Error(err))
+	}
+
+	return &tokenGen.UserInfoToken{
+		ID:       user.GUID,
+		Login:    user.Login,
+		Name:     user.Name,
+		Role:     user.Role,
+		APIKeyID: key.GUID,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -4,14 +4,47 @@ import (
 	"PetProjectGo/internal/models"
 	"PetProjectGo/internal/repository/mongoRepo"
 	"PetProjectGo/pkg/storage/mongodb"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"sort"
 )
 
 const categoryCollection = "categories"
 
+var ErrCategoryNotEmpty = fmt.Errorf("category has products, delete them with cascade or move them")
+var ErrInvalidDeleteMode = fmt.Errorf("cascade and move cannot be used together")
+var ErrMoveTargetNotFound = fmt.Errorf("category to move the products to not found")
+var ErrMoveTargetSame = fmt.Errorf("products cannot be moved to the deleted category")
+var ErrCategoryHasChildren = fmt.Errorf("category has subcategories, move or delete them first")
+var ErrParentCategoryNotFound = fmt.Errorf("parent category not found")
+var ErrCategoryCycle = fmt.Errorf("category cannot be moved under itself or its subcategories")
+
+// CategoryNode is a category with its subcategories.
+type CategoryNode struct {
+	*models.Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// DeleteCategoryOptions tells what to do with the products of a category
+// being deleted: delete them (Cascade) or move them to the category MoveTo.
+// Without either, a category with products is not deleted.
+type DeleteCategoryOptions struct {
+	Cascade bool
+	MoveTo  string
+}
+
+// DeleteCategoryResult counts the products deleted or moved with the category.
+type DeleteCategoryResult struct {
+	ProductsDeleted int64 `json:"products_deleted"`
+	ProductsMoved   int64 `json:"products_moved"`
+}
+
 type MarketCategoryService struct {
-	mongo *mongoRepo.CategoryRepoM
-	user  *UserService
+	mongo    *mongoRepo.CategoryRepoM
+	products *mongoRepo.ProductRepoM
+	user     *UserService
 }
 
 func NewMarketCategoryService(
@@ -24,8 +57,9 @@ func NewMarketCategoryService(
 		return nil, err
 	}
 	return &MarketCategoryService{
-		user:  userService,
-		mongo: mongoDb,
+		user:     userService,
+		mongo:    mongoDb,
+		products: mongoRepo.NewProductRepoM(userService.log, mongo, productCollection),
 	}, nil
 }
 
@@ -45,15 +79,225 @@ func (c *MarketCategoryService) GetAllCategories() ([]*models.Category, error) {
 	return categories, nil
 }
 
-func (c *MarketCategoryService) AddCategory(name string) error {
+// AddCategory adds a category under the parent category, or at the root
+// when parentGuid is empty.
+func (c *MarketCategoryService) AddCategory(name string, parentGuid string) error {
 	newCategory := &models.Category{
 		GUID: uuid.New().String(),
 		Name: name,
 	}
 
+	if parentGuid != "" {
+		ancestors, err := c.ancestorsUnder(parentGuid)
+		if err != nil {
+			return err
+		}
+		newCategory.ParentGuid = parentGuid
+		newCategory.Ancestors = ancestors
+	}
+
 	err := c.mongo.AddCategory(newCategory)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// GetCategoryTree returns the root categories with their subcategories,
+// sorted by name on every level.
+func (c *MarketCategoryService) GetCategoryTree() ([]*CategoryNode, error) {
+	categories, err := c.mongo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.GUID] = &CategoryNode{Category: category, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, node := range nodes {
+		parent, ok := nodes[node.ParentGuid]
+		if !ok {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	sortCategoryNodes(roots)
+	for _, node := range nodes {
+		sortCategoryNodes(node.Children)
+	}
+
+	return roots, nil
+}
+
+func sortCategoryNodes(nodes []*CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+}
+
+// GetCategoryPath returns the breadcrumbs of a category: the categories from
+// the root down to the category itself.
+func (c *MarketCategoryService) GetCategoryPath(guid string) ([]*models.Category, error) {
+	category, err := c.mongo.GetByGuid(guid)
+	if err != nil {
+		return nil, err
+	}
+	if len(category.Ancestors) == 0 {
+		return []*models.Category{category}, nil
+	}
+
+	ancestors, err := c.mongo.GetByGuids(category.Ancestors)
+	if err != nil {
+		return nil, err
+	}
+	byGuid := make(map[string]*models.Category, len(ancestors))
+	for _, ancestor := range ancestors {
+		byGuid[ancestor.GUID] = ancestor
+	}
+
+	path := make([]*models.Category, 0, len(category.Ancestors)+1)
+	for _, ancestorGuid := range category.Ancestors {
+		if ancestor, ok := byGuid[ancestorGuid]; ok {
+			path = append(path, ancestor)
+		}
+	}
+
+	return append(path, category), nil
+}
+
+// GetDescendantGuids returns the guids of the subcategories of a category,
+// of every level.
+func (c *MarketCategoryService) GetDescendantGuids(guid string) ([]string, error) {
+	return c.mongo.GetDescendantGuids(guid)
+}
+
+// MoveCategory moves a category, with its subcategories, under another
+// category, or to the root when parentGuid is empty.
+func (c *MarketCategoryService) MoveCategory(guid string, parentGuid string) (*models.Category, error) {
+	const op = "MarketCategoryService.MoveCategory"
+
+	_, err := c.mongo.GetByGuid(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	var ancestors []string
+	if parentGuid != "" {
+		if parentGuid == guid {
+			return nil, ErrCategoryCycle
+		}
+		ancestors, err = c.ancestorsUnder(parentGuid)
+		if err != nil {
+			return nil, err
+		}
+		for _, ancestor := range ancestors {
+			if ancestor == guid {
+				return nil, ErrCategoryCycle
+			}
+		}
+	}
+
+	category, err := c.mongo.Move(guid, parentGuid, ancestors)
+	if err != nil {
+		return nil, err
+	}
+
+	c.user.log.Info("Category moved", zap.String("op", op), zap.String("guid", guid), zap.String("parent", parentGuid))
+
+	return category, nil
+}
+
+// ancestorsUnder returns the ancestors of a category placed under the
+// parent category.
+func (c *MarketCategoryService) ancestorsUnder(parentGuid string) ([]string, error) {
+	parent, err := c.mongo.GetByGuid(parentGuid)
+	if errors.Is(err, mongoRepo.ErrCategoryNotFound) {
+		return nil, ErrParentCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors := make([]string, 0, len(parent.Ancestors)+1)
+	ancestors = append(ancestors, parent.Ancestors...)
+	return append(ancestors, parent.GUID), nil
+}
+
+func (c *MarketCategoryService) RenameCategory(guid string, name string) (*models.Category, error) {
+	return c.mongo.Rename(guid, name)
+}
+
+// DeleteCategory deletes the category. Its products are deleted or moved as
+// opts tell; otherwise ErrCategoryNotEmpty is returned while it has any. A
+// category with subcategories is not deleted.
+func (c *MarketCategoryService) DeleteCategory(guid string, opts DeleteCategoryOptions) (*DeleteCategoryResult, error) {
+	const op = "MarketCategoryService.DeleteCategory"
+
+	if opts.Cascade && opts.MoveTo != "" {
+		return nil, ErrInvalidDeleteMode
+	}
+
+	_, err := c.mongo.GetByGuid(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := c.mongo.CountChildren(guid)
+	if err != nil {
+		return nil, err
+	}
+	if children > 0 {
+		return nil, ErrCategoryHasChildren
+	}
+
+	result := &DeleteCategoryResult{}
+	switch {
+	case opts.MoveTo != "":
+		if opts.MoveTo == guid {
+			return nil, ErrMoveTargetSame
+		}
+		_, err = c.mongo.GetByGuid(opts.MoveTo)
+		if errors.Is(err, mongoRepo.ErrCategoryNotFound) {
+			return nil, ErrMoveTargetNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result.ProductsMoved, err = c.products.MoveToCategory(guid, opts.MoveTo)
+		if err != nil {
+			return nil, err
+		}
+	case opts.Cascade:
+		result.ProductsDeleted, err = c.products.DeleteByCategoryGuid(guid)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		count, err := c.products.CountByCategoryGuid(guid)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrCategoryNotEmpty
+		}
+	}
+
+	err = c.mongo.DeleteByGuid(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	c.user.log.Info(
+		"Category deleted",
+		zap.String("op", op),
+		zap.String("guid", guid),
+		zap.Int64("products_deleted", result.ProductsDeleted),
+		zap.Int64("products_moved", result.ProductsMoved),
+	)
+
+	return result, nil
+}
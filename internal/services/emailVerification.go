@@ -0,0 +1,95 @@
+package services
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/mongoRepo"
+	"PetProjectGo/internal/repository/postgresRepo"
+	"PetProjectGo/pkg/mail"
+	"PetProjectGo/pkg/tokenGen"
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+const tokenPurposeEmailVerification = "email_verification"
+
+var InvalidVerificationToken = fmt.Errorf("invalid or expired verification token")
+
+// ConfirmEmail marks the email of the token owner as verified.
+func (u *UserService) ConfirmEmail(token string) error {
+	const op = "UserService.ConfirmEmail"
+
+	timeNow := time.Now()
+	userToken, err := u.userTokens.ConsumeToken(tokenPurposeEmailVerification, tokenGen.HashToken(token), &timeNow)
+	if errors.Is(err, postgresRepo.ErrUserTokenNotFound) {
+		return InvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = u.mongo.UpdateEmailVerified(userToken.UserGUID, &timeNow)
+	if errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return InvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = u.userTokens.InvalidateTokens(userToken.UserGUID, tokenPurposeEmailVerification, &timeNow)
+	if err != nil {
+		return err
+	}
+
+	u.log.Info("Email verified", zap.String("op", op), zap.String("guid", userToken.UserGUID))
+
+	return nil
+}
+
+// ResendEmailVerification sends a new verification token unless the email is
+// already verified or a token was sent less than the resend interval ago.
+// Like RequestPasswordReset, it does not tell whether the login exists.
+func (u *UserService) ResendEmailVerification(login string) error {
+	const op = "UserService.ResendEmailVerification"
+
+	user, err := u.mongo.GetByLogin(login)
+	if errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil || user.Email == "" {
+		return nil
+	}
+
+	lastSentAt, err := u.userTokens.GetLastCreatedAt(user.GUID, tokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	if lastSentAt != nil && time.Since(*lastSentAt) < u.cfg.EmailVerificationResendInterval {
+		u.log.Info("Email verification resend throttled", zap.String("op", op), zap.String("guid", user.GUID))
+		return nil
+	}
+
+	return u.sendEmailVerification(user)
+}
+
+func (u *UserService) sendEmailVerification(user *models.User) error {
+	token, err := u.issueUserToken(user.GUID, tokenPurposeEmailVerification, u.cfg.EmailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(&mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"To confirm your email use this token: %s\n\nor open %s/auth/email/confirm?token=%s\n\n"+
+				"The token is valid for %s.",
+			token, u.cfg.PublicURL, token, u.cfg.EmailVerificationTokenTTL,
+		),
+	})
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"PetProjectGo/internal/config"
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/mongoRepo"
+	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/storage/mongodb"
+	"PetProjectGo/pkg/tokenGen"
+	"go.uber.org/zap"
+	"time"
+)
+
+const signingKeyCollection = "signing_keys"
+const hmacKeyID = "default"
+
+// KeyService owns the keys tokens are signed with. Asymmetric keys are kept
+// in MongoDB so that every instance signs with the same key; a new key is
+// generated every app.jwt.key_rotation_interval and the previous ones keep
+// verifying tokens until the longest-living token they signed has expired.
+type KeyService struct {
+	log   *logging.Logger
+	cfg   *config.AppConfig
+	mongo *mongoRepo.SigningKeyRepoM
+	keys  *tokenGen.KeySet
+}
+
+func NewKeyService(
+	log *logging.Logger,
+	cfg *config.AppConfig,
+	mongo *mongodb.MongoDB,
+) (*KeyService, error) {
+	const op = "KeyService.NewKeyService"
+
+	k := &KeyService{
+		log:  log,
+		cfg:  cfg,
+		keys: tokenGen.NewKeySet(nil),
+	}
+
+	switch cfg.JWT.Algorithm {
+	case tokenGen.AlgHS256:
+		log.Warn("Tokens are signed with a shared secret, JWKS will be empty", zap.String("op", op))
+		k.keys.Set(tokenGen.NewHMACKey(hmacKeyID, cfg.SecretKeyToken))
+		return k, nil
+	case tokenGen.AlgRS256, tokenGen.AlgEdDSA:
+	default:
+		return nil, tokenGen.ErrUnsupportedAlgorithm
+	}
+
+	k.mongo = mongoRepo.NewSigningKeyRepoM(log, mongo, signingKeyCollection)
+	err := k.mongo.CreateIndexesSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	err = k.Rotate()
+	if err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func (k *KeyService) KeySet() *tokenGen.KeySet {
+	return k.keys
+}
+
+func (k *KeyService) JWKS() tokenGen.JWKS {
+	return k.keys.JWKS()
+}
+
+// Rotate reloads the keys from MongoDB and generates a new active key when
+// there is none or the current one is older than the rotation interval.
+func (k *KeyService) Rotate() error {
+	const op = "KeyService.Rotate"
+
+	if k.mongo == nil {
+		return nil
+	}
+
+	stored, err := k.mongo.GetKeys(k.cfg.JWT.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	active := newestActiveKey(stored)
+	if active == nil || time.Since(*active.CreatedAt) >= k.cfg.JWT.KeyRotationInterval {
+		err = k.addKey()
+		if err != nil {
+			return err
+		}
+
+		stored, err = k.mongo.GetKeys(k.cfg.JWT.Algorithm)
+		if err != nil {
+			return err
+		}
+		active = newestActiveKey(stored)
+
+		k.log.Info("Signing key rotated", zap.String("op", op), zap.String("kid", active.Kid))
+	}
+
+	var activeKey *tokenGen.SigningKey
+	keys := make([]*tokenGen.SigningKey, 0, len(stored))
+	for _, key := range stored {
+		parsed, err := tokenGen.ParseKey(key.Kid, key.Algorithm, []byte(key.PrivateKey), *key.CreatedAt)
+		if err != nil {
+			k.log.Error("Error parsing signing key", zap.String("op", op), zap.String("kid", key.Kid), zap.Error(err))
+			continue
+		}
+		if key.Kid == active.Kid {
+			activeKey = parsed
+		}
+		keys = append(keys, parsed)
+	}
+
+	if activeKey == nil {
+		return tokenGen.ErrNoSigningKey
+	}
+
+	k.keys.Set(activeKey, keys...)
+
+	return nil
+}
+
+// RunRotation checks for due rotations and keys added by other instances
+// until the process exits.
+func (k *KeyService) RunRotation() {
+	const op = "KeyService.RunRotation"
+
+	if k.mongo == nil {
+		return
+	}
+
+	ticker := time.NewTicker(k.cfg.JWT.KeyCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := k.Rotate()
+		if err != nil {
+			k.log.Error("Error rotating signing keys", zap.String("op", op), zap.Error(err))
+		}
+	}
+}
+
+func (k *KeyService) addKey() error {
+	key, err := tokenGen.GenerateKey(k.cfg.JWT.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	privatePEM, err := key.MarshalPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	createdAt := key.CreatedAt
+	err = k.mongo.AddKey(&models.SigningKey{
+		Kid:        key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: string(privatePEM),
+		CreatedAt:  &createdAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	expiresAt := createdAt.Add(k.maxTokenLifetime())
+	return k.mongo.RetireKeys(key.Algorithm, &createdAt, &createdAt, &expiresAt)
+}
+
+func (k *KeyService) maxTokenLifetime() time.Duration {
+	access := k.cfg.TokenExpirationTimeMinutes * time.Minute
+	refresh := k.cfg.RefreshTokenExpirationTimeMinutes * time.Minute
+	if access > refresh {
+		return access + k.cfg.JWT.Leeway
+	}
+	return refresh + k.cfg.JWT.Leeway
+}
+
+func newestActiveKey(keys []*models.SigningKey) *models.SigningKey {
+	for _, key := range keys {
+		if key.RetiredAt == nil {
+			return key
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"PetProjectGo/internal/repository/postgresRepo"
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+var ErrAccountLocked = fmt.Errorf("account is temporarily locked")
+var ErrTooManyAttempts = fmt.Errorf("too many login attempts")
+
+// checkLoginThrottle refuses the attempt before the password is hashed if
+// the login or the IP is locked, or the login has to wait after recent
+// failures. IPs are not delayed since many users may share one.
+func (u *UserService) checkLoginThrottle(login string, ip string) error {
+	timeNow := time.Now()
+
+	wait, locked, err := u.loginWait(postgresRepo.LoginAttemptKeyLogin, login, &timeNow)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return fmt.Errorf("%w, retry in %s", ErrAccountLocked, wait.Round(time.Second))
+	}
+	if wait > 0 {
+		return fmt.Errorf("%w, retry in %s", ErrTooManyAttempts, wait.Round(time.Second))
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	wait, locked, err = u.loginWait(postgresRepo.LoginAttemptKeyIP, ip, &timeNow)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return fmt.Errorf("%w, retry in %s", ErrTooManyAttempts, wait.Round(time.Second))
+	}
+
+	return nil
+}
+
+// loginWait returns how long the key has to wait before the next attempt and
+// whether it is locked.
+func (u *UserService) loginWait(keyType string, key string, timeNow *time.Time) (time.Duration, bool, error) {
+	attempt, err := u.loginAttempts.Get(keyType, key)
+	if errors.Is(err, postgresRepo.ErrLoginAttemptNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(*timeNow) {
+		return attempt.LockedUntil.Sub(*timeNow), true, nil
+	}
+
+	if attempt.LastFailedAt.Before(timeNow.Add(-u.cfg.Lockout.Window)) {
+		return 0, false, nil
+	}
+
+	wait := attempt.LastFailedAt.Add(u.loginDelay(attempt.FailedCount)).Sub(*timeNow)
+	if wait < 0 {
+		wait = 0
+	}
+
+	return wait, false, nil
+}
+
+// loginDelay is the pause required after the given number of failures.
+func (u *UserService) loginDelay(failedCount int) time.Duration {
+	lockout := u.cfg.Lockout
+	if failedCount < lockout.DelayAfter {
+		return 0
+	}
+
+	delay := lockout.BaseDelay
+	for i := lockout.DelayAfter; i < failedCount && delay < lockout.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > lockout.MaxDelay {
+		delay = lockout.MaxDelay
+	}
+
+	return delay
+}
+
+// loginFailed counts a failed attempt for the login and the IP, locking them
+// when they reach the limit, and returns InvalidLoginPassword.
+func (u *UserService) loginFailed(login string, ip string) error {
+	const op = "UserService.loginFailed"
+
+	err := u.addLoginFailure(postgresRepo.LoginAttemptKeyLogin, login, u.cfg.Lockout.MaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	if ip != "" {
+		err = u.addLoginFailure(postgresRepo.LoginAttemptKeyIP, ip, u.cfg.Lockout.MaxAttemptsPerIP)
+		if err != nil {
+			return err
+		}
+	}
+
+	u.log.Info("Failed login", zap.String("op", op), zap.String("login", login), zap.String("ip", ip))
+
+	return InvalidLoginPassword
+}
+
+func (u *UserService) addLoginFailure(keyType string, key string, maxAttempts int) error {
+	const op = "UserService.addLoginFailure"
+
+	timeNow := time.Now()
+	windowStart := timeNow.Add(-u.cfg.Lockout.Window)
+
+	attempt, err := u.loginAttempts.AddFailure(keyType, key, &timeNow, &windowStart)
+	if err != nil {
+		return err
+	}
+
+	if maxAttempts <= 0 || attempt.FailedCount < maxAttempts {
+		return nil
+	}
+
+	lockedUntil := timeNow.Add(u.cfg.Lockout.Duration)
+	err = u.loginAttempts.Lock(keyType, key, &lockedUntil)
+	if err != nil {
+		return err
+	}
+
+	u.log.Warn("Login locked", zap.String("op", op), zap.String(keyType, key), zap.Time("until", lockedUntil))
+
+	return nil
+}
+
+// UnlockUser lifts the lockout of the user and forgets the failed attempts.
+func (u *UserService) UnlockUser(guid string) error {
+	user, err := u.mongo.GetByGuid(guid)
+	if err != nil {
+		return err
+	}
+
+	return u.loginAttempts.Reset(postgresRepo.LoginAttemptKeyLogin, user.Login)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"PetProjectGo/internal/config"
+	"PetProjectGo/pkg/passwordHash"
+	"PetProjectGo/pkg/passwordPolicy"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PasswordPolicyError lists the password rules a new password breaks.
+type PasswordPolicyError struct {
+	Violations []passwordPolicy.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	return "password does not meet the policy: " + strings.Join(rules, ", ")
+}
+
+func newPasswordPolicy(cfg *config.AppConfig) *passwordPolicy.Policy {
+	maxLength := cfg.PasswordPolicy.MaxLength
+	if cfg.PasswordHash.Algorithm == passwordHash.AlgBcrypt &&
+		(maxLength <= 0 || maxLength > passwordPolicy.BcryptMaxLength) {
+		maxLength = passwordPolicy.BcryptMaxLength
+	}
+
+	return &passwordPolicy.Policy{
+		MinLength:     cfg.PasswordMinLength,
+		MaxLength:     maxLength,
+		RequireUpper:  cfg.PasswordPolicy.RequireUpper,
+		RequireLower:  cfg.PasswordPolicy.RequireLower,
+		RequireDigit:  cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+		RejectLogin:   cfg.PasswordPolicy.RejectLogin,
+		RejectCommon:  cfg.PasswordPolicy.RejectCommon,
+	}
+}
+
+// CheckPassword returns the password rules the password breaks. Handlers use
+// it to report every problem at once; login may be empty if not known yet.
+func (u *UserService) CheckPassword(password string, login string) []passwordPolicy.Violation {
+	return u.passwordPolicy.Check(password, login)
+}
+
+// checkPasswordReuse refuses a password equal to one of the latest
+// app.password_policy.history_size passwords of the user.
+func (u *UserService) checkPasswordReuse(userGuid string, password string) error {
+	historySize := u.cfg.PasswordPolicy.HistorySize
+	if historySize <= 0 {
+		return nil
+	}
+
+	hashes, err := u.postgres.GetRecentPasswords(userGuid, historySize)
+	if err != nil {
+		return err
+	}
+
+	for _, hashedPassword := range hashes {
+		if u.checkHashPassword(password, hashedPassword) {
+			return &PasswordPolicyError{Violations: []passwordPolicy.Violation{
+				{Rule: passwordPolicy.RuleReused, Param: strconv.Itoa(historySize)},
+			}}
+		}
+	}
+
+	return nil
+}
+
+// setPassword stores a new password of an existing user, keeping the old one
+// in the history.
+func (u *UserService) setPassword(userGuid string, password string) error {
+	hashedPassword, err := u.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	timeNow := time.Now()
+	return u.postgres.UpdatePassword(
+		userGuid, hashedPassword, u.passwordHasher.Algorithm(), u.cfg.PasswordPolicy.HistorySize, &timeNow,
+	)
+}
+
+func (u *UserService) checkPassword(password string, login string) error {
+	violations := u.CheckPassword(password, login)
+	if len(violations) != 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
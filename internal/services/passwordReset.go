@@ -0,0 +1,176 @@
+package services
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/mongoRepo"
+	"PetProjectGo/internal/repository/postgresRepo"
+	"PetProjectGo/pkg/mail"
+	"PetProjectGo/pkg/tokenGen"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+const tokenPurposePasswordReset = "password_reset"
+
+var InvalidResetToken = fmt.Errorf("invalid or expired reset token")
+
+// RequestPasswordReset mails a single-use reset token to the user, at most
+// once per app.password_reset_interval. It does not tell whether the login
+// exists, so it cannot be used to probe accounts: only the login lookup is
+// done on the request, the token is issued and mailed in the background.
+func (u *UserService) RequestPasswordReset(login string) error {
+	const op = "UserService.RequestPasswordReset"
+
+	user, err := u.mongo.GetByLogin(login)
+	if errors.Is(err, mongoRepo.ErrUserNotFound) {
+		u.log.Info("Password reset requested for unknown login", zap.String("op", op))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.Email == "" {
+		u.log.Info("Password reset requested for user without email", zap.String("op", op), zap.String("guid", user.GUID))
+		return nil
+	}
+
+	go u.sendPasswordReset(user)
+
+	return nil
+}
+
+// sendPasswordReset issues and mails a reset token unless one was issued
+// less than app.password_reset_interval ago. Errors are only logged.
+func (u *UserService) sendPasswordReset(user *models.User) {
+	const op = "UserService.sendPasswordReset"
+
+	lastSentAt, err := u.userTokens.GetLastCreatedAt(user.GUID, tokenPurposePasswordReset)
+	if err != nil {
+		u.log.Error("Error checking last password reset", zap.String("op", op), zap.String("guid", user.GUID), zap.Error(err))
+		return
+	}
+	if lastSentAt != nil && time.Since(*lastSentAt) < u.cfg.PasswordResetInterval {
+		u.log.Info("Password reset throttled", zap.String("op", op), zap.String("guid", user.GUID))
+		return
+	}
+
+	token, err := u.issueUserToken(user.GUID, tokenPurposePasswordReset, u.cfg.PasswordResetTokenTTL)
+	if err != nil {
+		u.log.Error("Error issuing password reset token", zap.String("op", op), zap.String("guid", user.GUID), zap.Error(err))
+		return
+	}
+
+	err = u.mailer.Send(&mail.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"To set a new password, send it with this token to %s/auth/password/reset/confirm:\n\n%s\n\n"+
+				"The token is valid for %s. If you did not ask for a reset, ignore this message.",
+			u.cfg.PublicURL, token, u.cfg.PasswordResetTokenTTL,
+		),
+	})
+	if err != nil {
+		u.log.Error("Error mailing password reset token", zap.String("op", op), zap.String("guid", user.GUID), zap.Error(err))
+	}
+}
+
+// ConfirmPasswordReset sets a new password using a token from
+// RequestPasswordReset and ends every session of the user.
+func (u *UserService) ConfirmPasswordReset(token string, password string) error {
+	const op = "UserService.ConfirmPasswordReset"
+
+	timeNow := time.Now()
+	tokenHash := tokenGen.HashToken(token)
+
+	// The password is checked before the token is spent, so a rejected
+	// password does not force the user to request another token.
+	userToken, err := u.userTokens.GetToken(tokenPurposePasswordReset, tokenHash, &timeNow)
+	if errors.Is(err, postgresRepo.ErrUserTokenNotFound) {
+		return InvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := u.mongo.GetByGuid(userToken.UserGUID)
+	if errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return InvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = u.checkPassword(password, user.Login)
+	if err != nil {
+		return err
+	}
+
+	err = u.checkPasswordReuse(user.GUID, password)
+	if err != nil {
+		return err
+	}
+
+	userToken, err = u.userTokens.ConsumeToken(tokenPurposePasswordReset, tokenHash, &timeNow)
+	if errors.Is(err, postgresRepo.ErrUserTokenNotFound) {
+		return InvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = u.setPassword(userToken.UserGUID, password)
+	if err != nil {
+		return err
+	}
+
+	err = u.mongo.TouchUpdatedAt(userToken.UserGUID, &timeNow)
+	if err != nil {
+		return err
+	}
+
+	err = u.userTokens.InvalidateTokens(userToken.UserGUID, tokenPurposePasswordReset, &timeNow)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.ForceLogout(userToken.UserGUID)
+	if err != nil {
+		return err
+	}
+
+	u.log.Info("Password reset", zap.String("op", op), zap.String("guid", userToken.UserGUID))
+
+	return nil
+}
+
+// issueUserToken stores the hash of a new random token and returns the token.
+func (u *UserService) issueUserToken(userGuid string, purpose string, ttl time.Duration) (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	timeNow := time.Now()
+	expiresAt := timeNow.Add(ttl)
+	err = u.userTokens.AddToken(&postgresRepo.UserToken{
+		GUID:      uuid.New().String(),
+		UserGUID:  userGuid,
+		Purpose:   purpose,
+		TokenHash: tokenGen.HashToken(token),
+		ExpiresAt: &expiresAt,
+		CreatedAt: &timeNow,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
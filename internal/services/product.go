@@ -4,11 +4,64 @@ import (
 	"PetProjectGo/internal/models"
 	"PetProjectGo/internal/repository/mongoRepo"
 	"PetProjectGo/pkg/storage/mongodb"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"time"
 )
 
 const productCollection = "products"
 
+const (
+	productListDefaultLimit = 20
+	productListMaxLimit     = 100
+)
+
+var ErrInvalidSort = fmt.Errorf("invalid sort, use name, price or created, prefixed with - for descending order")
+var ErrInvalidCursor = fmt.Errorf("invalid cursor")
+var ErrInvalidPriceRange = fmt.Errorf("min_price is greater than max_price")
+var ErrInvalidLimit = fmt.Errorf("limit must be between 1 and %d", productListMaxLimit)
+
+// productSorts maps the sort names of the listing to the product fields.
+var productSorts = map[string]string{
+	"name":    mongoRepo.ProductSortName,
+	"price":   mongoRepo.ProductSortPrice,
+	"created": mongoRepo.ProductSortCreatedAt,
+}
+
+// ProductListM selects the products to list. IncludeDescendants adds the
+// products of the subcategories of the category. Sort is a key of
+// productSorts, "-" prefixed for descending order; Cursor is the NextCursor
+// of the page before.
+type ProductListM struct {
+	CategoryGuid       string
+	IncludeDescendants bool
+	MinPrice           *int
+	MaxPrice           *int
+	InStock            bool
+	NamePrefix         string
+	Sort               string
+	Cursor             string
+	Limit              int
+}
+
+// ProductPage is a page of products. NextCursor is empty on the last page.
+type ProductPage struct {
+	Products   []*models.Product `json:"products"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// productCursor is what a cursor encodes: the sort it was made for and the
+// sort value and guid of the last product of the page.
+type productCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	GUID  string          `json:"g"`
+}
+
 type NewProductM struct {
 	CategoryGuid string `json:"category_id" mapstructure:"category_id"`
 	Name         string `json:"name"`
@@ -17,6 +70,15 @@ type NewProductM struct {
 	Quantity     int    `json:"quantity,omitempty" default:"1"`
 }
 
+// ProductUpdateM holds the fields of a product to change; nil ones are kept.
+type ProductUpdateM struct {
+	CategoryGuid *string
+	Name         *string
+	Description  *string
+	Price        *int
+	Quantity     *int
+}
+
 type MarketProductService struct {
 	mongo    *mongoRepo.ProductRepoM
 	category *MarketCategoryService
@@ -31,6 +93,11 @@ func NewMarketProductService(
 	if err != nil {
 		return nil, err
 	}
+	timeNow := time.Now()
+	err = mongoDb.BackfillCreatedAt(&timeNow)
+	if err != nil {
+		return nil, err
+	}
 	return &MarketProductService{
 		category: categoryService,
 		mongo:    mongoDb,
@@ -60,6 +127,7 @@ func (p *MarketProductService) AddProduct(product *NewProductM) (*models.Product
 		return nil, err
 	}
 	userGuid := uuid.New().String()
+	timeNow := time.Now()
 	newProduct := &models.Product{
 		GUID:         userGuid,
 		CategoryGuid: product.CategoryGuid,
@@ -67,6 +135,7 @@ func (p *MarketProductService) AddProduct(product *NewProductM) (*models.Product
 		Description:  product.Description,
 		Price:        product.Price,
 		Quantity:     product.Quantity,
+		CreatedAt:    &timeNow,
 	}
 	err = p.mongo.AddNewProduct(newProduct)
 	if err != nil {
@@ -74,3 +143,177 @@ func (p *MarketProductService) AddProduct(product *NewProductM) (*models.Product
 	}
 	return newProduct, nil
 }
+
+func (p *MarketProductService) GetProduct(guid string) (*models.Product, error) {
+	return p.mongo.GetByGuid(guid)
+}
+
+// UpdateProduct changes the given fields of the product. Moving it to another
+// category requires that category to exist.
+func (p *MarketProductService) UpdateProduct(guid string, update *ProductUpdateM) (*models.Product, error) {
+	set := bson.M{}
+	if update.CategoryGuid != nil {
+		_, err := p.category.GetByGuid(*update.CategoryGuid)
+		if err != nil {
+			return nil, err
+		}
+		set["category_id"] = *update.CategoryGuid
+	}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.Price != nil {
+		set["price"] = *update.Price
+	}
+	if update.Quantity != nil {
+		set["quantity"] = *update.Quantity
+	}
+
+	if len(set) == 0 {
+		return p.mongo.GetByGuid(guid)
+	}
+
+	return p.mongo.UpdateProduct(guid, set)
+}
+
+func (p *MarketProductService) DeleteProduct(guid string) error {
+	return p.mongo.DeleteByGuid(guid)
+}
+
+// ListProducts returns a page of the products matching the filters.
+func (p *MarketProductService) ListProducts(list *ProductListM) (*ProductPage, error) {
+	sort := list.Sort
+	if sort == "" {
+		sort = "name"
+	}
+	descending := strings.HasPrefix(sort, "-")
+	sortField, ok := productSorts[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	limit := list.Limit
+	if limit == 0 {
+		limit = productListDefaultLimit
+	}
+	if limit < 0 || limit > productListMaxLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	if list.MinPrice != nil && list.MaxPrice != nil && *list.MinPrice > *list.MaxPrice {
+		return nil, ErrInvalidPriceRange
+	}
+
+	var categoryGuids []string
+	if list.CategoryGuid != "" {
+		_, err := p.category.GetByGuid(list.CategoryGuid)
+		if err != nil {
+			return nil, err
+		}
+		categoryGuids = []string{list.CategoryGuid}
+		if list.IncludeDescendants {
+			descendants, err := p.category.GetDescendantGuids(list.CategoryGuid)
+			if err != nil {
+				return nil, err
+			}
+			categoryGuids = append(categoryGuids, descendants...)
+		}
+	}
+
+	query := &mongoRepo.ProductQuery{
+		CategoryGuids: categoryGuids,
+		MinPrice:      list.MinPrice,
+		MaxPrice:      list.MaxPrice,
+		InStock:       list.InStock,
+		NamePrefix:    list.NamePrefix,
+		SortField:     sortField,
+		Descending:    descending,
+		// One more than asked tells whether there is a next page.
+		Limit: limit + 1,
+	}
+	if list.Cursor != "" {
+		after, err := decodeProductCursor(list.Cursor, sort, sortField)
+		if err != nil {
+			return nil, err
+		}
+		query.After = after
+	}
+
+	products, err := p.mongo.ListProducts(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > limit {
+		page.Products = products[:limit]
+		page.NextCursor, err = encodeProductCursor(page.Products[limit-1], sort, sortField)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+func encodeProductCursor(product *models.Product, sort string, sortField string) (string, error) {
+	var value interface{}
+	switch sortField {
+	case mongoRepo.ProductSortName:
+		value = product.Name
+	case mongoRepo.ProductSortPrice:
+		value = product.Price
+	case mongoRepo.ProductSortCreatedAt:
+		value = product.CreatedAt
+	}
+
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	cursor, err := json.Marshal(productCursor{Sort: sort, Value: rawValue, GUID: product.GUID})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cursor), nil
+}
+
+// decodeProductCursor returns the position a cursor points at. A cursor is
+// only valid with the sort it was made for.
+func decodeProductCursor(encoded string, sort string, sortField string) (*mongoRepo.ProductCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor productCursor
+	err = json.Unmarshal(raw, &cursor)
+	if err != nil || cursor.Sort != sort || cursor.GUID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	var value interface{}
+	switch sortField {
+	case mongoRepo.ProductSortName:
+		var name string
+		err = json.Unmarshal(cursor.Value, &name)
+		value = name
+	case mongoRepo.ProductSortPrice:
+		var price int
+		err = json.Unmarshal(cursor.Value, &price)
+		value = price
+	case mongoRepo.ProductSortCreatedAt:
+		var createdAt time.Time
+		err = json.Unmarshal(cursor.Value, &createdAt)
+		value = createdAt
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &mongoRepo.ProductCursor{Value: value, GUID: cursor.GUID}, nil
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/mongoRepo"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	productSearchMaxLength    = 200
+	productSearchMinPrefix    = 2
+	productSearchFragmentSize = 160
+	productSearchContextSize  = 60
+)
+
+// wordStart and wordEnd anchor a pattern to the edges of a word. They read
+// the same to Mongo and to Go.
+const (
+	wordStart = `(?:^|[^\p{L}\p{N}])`
+	wordEnd   = `(?:$|[^\p{L}\p{N}])`
+)
+
+var ErrInvalidSearch = fmt.Errorf("invalid search")
+
+var searchTokenRegexp = regexp.MustCompile(`"([^"]*)"|\S+`)
+
+// ProductSearchM is a product search. Query holds words, "quoted phrases",
+// prefixes ending with * and words to exclude starting with -.
+type ProductSearchM struct {
+	Query        string
+	CategoryGuid string
+	Offset       int
+	Limit        int
+}
+
+// ProductHit is a found product. Highlights holds the matched name and a
+// fragment of the matched description, HTML escaped, with the matches in
+// <em> tags.
+type ProductHit struct {
+	*models.Product
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// ProductSearchPage is a page of found products, the most relevant first.
+// NextOffset is empty on the last page.
+type ProductSearchPage struct {
+	Products   []*ProductHit `json:"products"`
+	NextOffset int           `json:"next_offset,omitempty"`
+}
+
+type productSearch struct {
+	phrases  []string
+	words    []string
+	prefixes []string
+	excluded []string
+}
+
+// SearchProducts finds the products whose name or description match the
+// search. Words and phrases go to the text index, which ranks the products;
+// every prefix must match too. A search of prefixes only is ranked by the
+// matches of the prefixes in the name and the description.
+func (p *MarketProductService) SearchProducts(search *ProductSearchM) (*ProductSearchPage, error) {
+	parsed, err := parseProductSearch(search.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := search.Limit
+	if limit == 0 {
+		limit = productListDefaultLimit
+	}
+	if limit < 0 || limit > productListMaxLimit {
+		return nil, ErrInvalidLimit
+	}
+	if search.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidSearch)
+	}
+
+	if search.CategoryGuid != "" {
+		_, err = p.category.GetByGuid(search.CategoryGuid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := &mongoRepo.ProductSearchQuery{
+		Text:         parsed.text(),
+		Patterns:     parsed.patterns(),
+		Excluded:     parsed.excludedPatterns(),
+		CategoryGuid: search.CategoryGuid,
+		Skip:         search.Offset,
+		// One more than asked tells whether there is a next page.
+		Limit: limit + 1,
+	}
+
+	hits, err := p.mongo.SearchProducts(query)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := parsed.matcher()
+	products := make([]*ProductHit, 0, len(hits))
+	for _, hit := range hits {
+		products = append(products, newProductHit(hit, matcher))
+	}
+
+	page := &ProductSearchPage{Products: products}
+	if len(products) > limit {
+		page.Products = products[:limit]
+		page.NextOffset = search.Offset + limit
+	}
+
+	return page, nil
+}
+
+func parseProductSearch(query string) (*productSearch, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) > productSearchMaxLength {
+		return nil, fmt.Errorf("%w: the query is longer than %d characters", ErrInvalidSearch, productSearchMaxLength)
+	}
+
+	parsed := &productSearch{}
+	for _, match := range searchTokenRegexp.FindAllStringSubmatch(query, -1) {
+		token := match[0]
+		if len(token) > 1 && strings.HasPrefix(token, `"`) && strings.HasSuffix(token, `"`) {
+			phrase := strings.Join(strings.Fields(match[1]), " ")
+			if phrase != "" {
+				parsed.phrases = append(parsed.phrases, phrase)
+			}
+			continue
+		}
+
+		token = strings.Trim(token, `"`)
+		switch {
+		case strings.HasPrefix(token, "-"):
+			token = strings.Trim(token[1:], "*")
+			if token != "" {
+				parsed.excluded = append(parsed.excluded, token)
+			}
+		case strings.HasSuffix(token, "*"):
+			token = strings.TrimRight(token, "*")
+			if utf8.RuneCountInString(token) < productSearchMinPrefix {
+				return nil, fmt.Errorf("%w: a prefix needs at least %d characters", ErrInvalidSearch, productSearchMinPrefix)
+			}
+			parsed.prefixes = append(parsed.prefixes, token)
+		case token != "":
+			parsed.words = append(parsed.words, token)
+		}
+	}
+
+	if len(parsed.phrases) == 0 && len(parsed.words) == 0 && len(parsed.prefixes) == 0 {
+		return nil, fmt.Errorf("%w: nothing to search for", ErrInvalidSearch)
+	}
+
+	return parsed, nil
+}
+
+// text returns the $text search of the words and phrases.
+func (s *productSearch) text() string {
+	terms := make([]string, 0, len(s.phrases)+len(s.words))
+	for _, phrase := range s.phrases {
+		terms = append(terms, `"`+phrase+`"`)
+	}
+	terms = append(terms, s.words...)
+
+	return strings.Join(terms, " ")
+}
+
+// patterns returns a regular expression per prefix, matching the start of a word.
+func (s *productSearch) patterns() []string {
+	patterns := make([]string, 0, len(s.prefixes))
+	for _, prefix := range s.prefixes {
+		patterns = append(patterns, wordStart+regexp.QuoteMeta(prefix))
+	}
+
+	return patterns
+}
+
+// excludedPatterns returns a regular expression per excluded word, matching
+// the whole word.
+func (s *productSearch) excludedPatterns() []string {
+	patterns := make([]string, 0, len(s.excluded))
+	for _, word := range s.excluded {
+		patterns = append(patterns, wordStart+regexp.QuoteMeta(word)+wordEnd)
+	}
+
+	return patterns
+}
+
+// matcher returns the regular expression finding what the search matched,
+// up to the end of the word. Its first group is the match itself.
+func (s *productSearch) matcher() *regexp.Regexp {
+	var terms []string
+	for _, phrase := range s.phrases {
+		words := strings.Fields(phrase)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		terms = append(terms, strings.Join(words, `\s+`))
+	}
+	for _, term := range append(append([]string{}, s.words...), s.prefixes...) {
+		terms = append(terms, regexp.QuoteMeta(term))
+	}
+	// The longest term first, so that it wins over the terms it starts with.
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+
+	return regexp.MustCompile(`(?i)` + wordStart + `((?:` + strings.Join(terms, "|") + `)[\p{L}\p{N}]*)`)
+}
+
+func newProductHit(hit *mongoRepo.ProductSearchHit, matcher *regexp.Regexp) *ProductHit {
+	product := hit.Product
+	nameMatches := matcher.FindAllStringSubmatchIndex(product.Name, -1)
+	descriptionMatches := matcher.FindAllStringSubmatchIndex(product.Description, -1)
+
+	result := &ProductHit{Product: &product, Score: hit.Score}
+
+	highlights := map[string]string{}
+	if len(nameMatches) > 0 {
+		highlights["name"] = highlight(product.Name, nameMatches, 0, len(product.Name))
+	}
+	if len(descriptionMatches) > 0 {
+		start, end := fragmentBounds(product.Description, descriptionMatches[0][2])
+		highlights["description"] = highlight(product.Description, descriptionMatches, start, end)
+	}
+	if len(highlights) > 0 {
+		result.Highlights = highlights
+	}
+
+	return result
+}
+
+// fragmentBounds returns the bounds of the fragment of a text shown around
+// a match at the given offset.
+func fragmentBounds(text string, at int) (int, int) {
+	if len(text) <= productSearchFragmentSize {
+		return 0, len(text)
+	}
+
+	start := max(at-productSearchContextSize, 0)
+	end := start + productSearchFragmentSize
+	if end > len(text) {
+		end = len(text)
+		start = end - productSearchFragmentSize
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	return start, end
+}
+
+// highlight HTML escapes text[start:end], wraps the matches within it in
+// <em> tags and marks the ends cut from the text with an ellipsis.
+func highlight(text string, matches [][]int, start int, end int) string {
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	last := start
+	for _, match := range matches {
+		from, to := match[2], match[3]
+		if from < last || to > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:from]))
+		b.WriteString("<em>" + html.EscapeString(text[from:to]) + "</em>")
+		last = to
+	}
+	b.WriteString(html.EscapeString(text[last:end]))
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/postgresRepo"
+	"PetProjectGo/pkg/tokenGen"
+	"go.uber.org/zap"
+	"time"
+)
+
+// UpdateProfile changes the name of the user. Nil fields are left as they are.
+func (u *UserService) UpdateProfile(userGuid string, name *string, lastName *string) (*models.User, error) {
+	timeNow := time.Now()
+	return u.mongo.UpdateProfile(userGuid, name, lastName, &timeNow)
+}
+
+// ChangePassword replaces the password of the logged-in user after checking
+// the current one, and ends every other session of the user.
+func (u *UserService) ChangePassword(userInfo *tokenGen.UserInfoToken, currentPassword string, password string) (int64, error) {
+	const op = "UserService.ChangePassword"
+
+	err := u.reauthenticate(userInfo, currentPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	err = u.checkPassword(password, userInfo.Login)
+	if err != nil {
+		return 0, err
+	}
+
+	err = u.checkPasswordReuse(userInfo.ID, password)
+	if err != nil {
+		return 0, err
+	}
+
+	err = u.setPassword(userInfo.ID, password)
+	if err != nil {
+		return 0, err
+	}
+
+	timeNow := time.Now()
+	err = u.mongo.TouchUpdatedAt(userInfo.ID, &timeNow)
+	if err != nil {
+		return 0, err
+	}
+
+	// A reset link sent before the change must not undo it.
+	err = u.userTokens.InvalidateTokens(userInfo.ID, tokenPurposePasswordReset, &timeNow)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := u.RevokeOtherSessions(userInfo.ID, userInfo.SessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	u.log.Info("Password changed", zap.String("op", op), zap.String("guid", userInfo.ID), zap.Int64("revoked", revoked))
+
+	return revoked, nil
+}
+
+// reauthenticate checks the password of a logged-in user before a sensitive
+// change. Wrong passwords count towards the login lockout like failed logins.
+func (u *UserService) reauthenticate(userInfo *tokenGen.UserInfoToken, password string) error {
+	err := u.checkLoginThrottle(userInfo.Login, "")
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := u.postgres.GetHashPasswordByGuid(userInfo.ID)
+	if err != nil {
+		return err
+	}
+	if !u.checkHashPassword(password, hashedPassword) {
+		err = u.addLoginFailure(postgresRepo.LoginAttemptKeyLogin, userInfo.Login, u.cfg.Lockout.MaxAttempts)
+		if err != nil {
+			return err
+		}
+		return InvalidPassword
+	}
+
+	return nil
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"PetProjectGo/internal/config"
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/mongoRepo"
+	"PetProjectGo/internal/repository/postgresRepo"
+	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/passwordHash"
+	"PetProjectGo/pkg/storage/mongodb"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+const registrationOutboxBatch = 100
+
+var ErrRegistrationLoginTaken = fmt.Errorf("login was taken by another user")
+
+// RegistrationService keeps the users in Mongo and their credentials in
+// Postgres consistent. A registration is first written to Postgres together
+// with an outbox entry holding the Mongo user, in one transaction; the entry
+// is then applied to Mongo right away or, if that fails, by RunOutbox.
+type RegistrationService struct {
+	log      *logging.Logger
+	cfg      *config.AppConfig
+	mongo    *mongoRepo.UserRepoM
+	postgres *postgresRepo.UserRepoP
+	outbox   *postgresRepo.RegistrationOutboxRepoP
+
+	passwordHasher *passwordHash.Hasher
+}
+
+// RepairReport lists the guids of the users Repair found out of sync, by
+// what was (or, in a dry run, would be) done about them.
+type RepairReport struct {
+	DryRun              bool     `json:"dry_run"`
+	Replayed            []string `json:"replayed"`
+	DeletedFromPostgres []string `json:"deleted_from_postgres"`
+	DeletedFromMongo    []string `json:"deleted_from_mongo"`
+	CredentialsCreated  []string `json:"credentials_created"`
+	Failed              []string `json:"failed"`
+}
+
+func NewRegistrationService(
+	log *logging.Logger,
+	cfg *config.AppConfig,
+	mongo *mongodb.MongoDB,
+	postgres *sqlx.DB,
+) (*RegistrationService, error) {
+	hasher, err := newPasswordHasher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegistrationService{
+		log:      log,
+		cfg:      cfg,
+		mongo:    mongoRepo.NewUserRepoM(log, mongo, userCollection),
+		postgres: postgresRepo.NewUserRepoP(log, postgres),
+		outbox:   postgresRepo.NewRegistrationOutboxRepoP(log, postgres),
+
+		passwordHasher: hasher,
+	}, nil
+}
+
+// IsLoginPending reports whether a registration with the login is stored but
+// not in Mongo yet.
+func (r *RegistrationService) IsLoginPending(login string) (bool, error) {
+	return r.outbox.HasPendingLogin(login)
+}
+
+// Register stores the credentials and the outbox entry of the user and then
+// tries to add the user to Mongo. It reports whether the user is in Mongo;
+// when not, the outbox worker retries and the error is only logged.
+func (r *RegistrationService) Register(user *models.User, credentials *postgresRepo.User) (bool, error) {
+	const op = "RegistrationService.Register"
+
+	payload, err := json.Marshal(user)
+	if err != nil {
+		return false, err
+	}
+
+	registration := &postgresRepo.Registration{
+		UserGUID:  user.GUID,
+		Login:     user.Login,
+		Payload:   payload,
+		CreatedAt: user.CreatedAt,
+	}
+	err = r.postgres.AddUserWithOutbox(credentials, registration)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.apply(registration)
+	if errors.Is(err, ErrRegistrationLoginTaken) {
+		return false, ErrUserAlreadyExists
+	}
+	if err != nil {
+		r.log.Error("Error applying registration, left to the outbox", zap.String("op", op), zap.Error(err))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// ProcessOutbox applies the pending registrations and returns how many were
+// applied.
+func (r *RegistrationService) ProcessOutbox() (int, error) {
+	const op = "RegistrationService.ProcessOutbox"
+
+	registrations, err := r.outbox.GetPending(registrationOutboxBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, registration := range registrations {
+		err = r.apply(registration)
+		if err != nil {
+			r.log.Error(
+				"Error applying registration",
+				zap.String("op", op),
+				zap.String("guid", registration.UserGUID),
+				zap.Int("attempts", registration.Attempts+1),
+				zap.Error(err),
+			)
+			continue
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// RunOutbox applies pending registrations every
+// app.registration_outbox_interval. It blocks, so run it in a goroutine.
+func (r *RegistrationService) RunOutbox() {
+	const op = "RegistrationService.RunOutbox"
+
+	ticker := time.NewTicker(r.cfg.RegistrationOutboxInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		applied, err := r.ProcessOutbox()
+		if err != nil {
+			r.log.Error("Error processing registration outbox", zap.String("op", op), zap.Error(err))
+			continue
+		}
+		if applied > 0 {
+			r.log.Info("Registrations applied", zap.String("op", op), zap.Int("count", applied))
+		}
+	}
+}
+
+// apply adds the user of the registration to Mongo and marks the entry
+// processed. It can be repeated: a user already in Mongo is left as is. If
+// the login was taken by another user meanwhile, the registration is undone.
+func (r *RegistrationService) apply(registration *postgresRepo.Registration) error {
+	const op = "RegistrationService.apply"
+
+	var user models.User
+	err := json.Unmarshal(registration.Payload, &user)
+	if err != nil {
+		return r.markFailed(registration, err)
+	}
+
+	existing, err := r.mongo.GetByLogin(user.Login)
+	if err != nil && !errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return r.markFailed(registration, err)
+	}
+	if existing == nil || existing.GUID == user.GUID {
+		_, err = r.mongo.AddUserIfMissing(&user)
+		if err == nil {
+			timeNow := time.Now()
+			return r.outbox.MarkProcessed(registration.UserGUID, &timeNow)
+		}
+		if !errors.Is(err, mongoRepo.ErrUserLoginTaken) {
+			return r.markFailed(registration, err)
+		}
+	}
+
+	r.log.Warn(
+		"Registration login taken, registration undone",
+		zap.String("op", op),
+		zap.String("guid", user.GUID),
+		zap.String("login", user.Login),
+	)
+	err = r.postgres.DeleteUser(user.GUID, "")
+	if err != nil {
+		return r.markFailed(registration, err)
+	}
+
+	return ErrRegistrationLoginTaken
+}
+
+func (r *RegistrationService) markFailed(registration *postgresRepo.Registration, err error) error {
+	const op = "RegistrationService.markFailed"
+
+	err2 := r.outbox.MarkFailed(registration.UserGUID, err.Error())
+	if err2 != nil {
+		r.log.Error("Error marking registration failed", zap.String("op", op), zap.Error(err2))
+	}
+
+	return err
+}
+
+// Repair finds users that are only in one of the stores and fixes them:
+//   - pending registrations are applied;
+//   - credentials without a Mongo user and without a pending registration
+//     are deleted;
+//   - Mongo users whose deletion is due are deleted;
+//   - other Mongo users without credentials get a random password they do
+//     not know, so they have to reset it.
+//
+// With dryRun nothing is changed and the report tells what would be done.
+func (r *RegistrationService) Repair(dryRun bool) (*RepairReport, error) {
+	const op = "RegistrationService.Repair"
+
+	report := &RepairReport{DryRun: dryRun}
+
+	for !dryRun {
+		applied, err := r.ProcessOutbox()
+		if err != nil {
+			return nil, err
+		}
+		if applied == 0 {
+			break
+		}
+	}
+
+	// Postgres is read before Mongo: a registration applied in between is
+	// then in Mongo, not missing from it. Registrations are applied while the
+	// snapshots are taken, so every user is checked again before a change.
+	snapshotAt := time.Now()
+	guids, err := r.postgres.GetAllGuids()
+	if err != nil {
+		return nil, err
+	}
+	users, err := r.mongo.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	inMongo := make(map[string]bool, len(users))
+	for _, user := range users {
+		inMongo[user.GUID] = true
+	}
+	inPostgres := make(map[string]bool, len(guids))
+	for _, guid := range guids {
+		inPostgres[guid] = true
+	}
+
+	for _, guid := range guids {
+		if inMongo[guid] {
+			continue
+		}
+
+		registration, err := r.outbox.Get(guid)
+		if err != nil && !errors.Is(err, postgresRepo.ErrRegistrationNotFound) {
+			return nil, err
+		}
+		if registration != nil && registration.ProcessedAt == nil {
+			if dryRun {
+				report.Replayed = append(report.Replayed, guid)
+				continue
+			}
+			// Still failing after ProcessOutbox, or registered just now.
+			r.log.Warn("Registration still pending", zap.String("op", op), zap.String("guid", guid))
+			report.Failed = append(report.Failed, guid)
+			continue
+		}
+		if registration != nil && !registration.ProcessedAt.Before(snapshotAt) {
+			// Applied during the snapshots, so in Mongo by now.
+			continue
+		}
+
+		_, err = r.mongo.GetByGuid(guid)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, mongoRepo.ErrUserNotFound) {
+			return nil, err
+		}
+
+		report.DeletedFromPostgres = append(report.DeletedFromPostgres, guid)
+		if dryRun {
+			continue
+		}
+		err = r.postgres.DeleteUser(guid, "")
+		if err != nil {
+			r.log.Error("Error deleting orphaned credentials", zap.String("op", op), zap.Error(err))
+			report.Failed = append(report.Failed, guid)
+		}
+	}
+
+	timeNow := time.Now()
+	for _, user := range users {
+		if inPostgres[user.GUID] {
+			continue
+		}
+		// Registered after the Postgres snapshot.
+		hasCredentials, err := r.postgres.HasUser(user.GUID)
+		if err != nil {
+			return nil, err
+		}
+		if hasCredentials {
+			continue
+		}
+
+		if user.DeletionScheduledAt != nil && !user.DeletionScheduledAt.After(timeNow) {
+			report.DeletedFromMongo = append(report.DeletedFromMongo, user.GUID)
+			if dryRun {
+				continue
+			}
+			err = r.mongo.DeleteByGuid(user.GUID)
+			if err != nil {
+				report.Failed = append(report.Failed, user.GUID)
+			}
+			continue
+		}
+
+		report.CredentialsCreated = append(report.CredentialsCreated, user.GUID)
+		if dryRun {
+			continue
+		}
+		err = r.addRandomCredentials(user.GUID, &timeNow)
+		if err != nil {
+			r.log.Error("Error creating credentials", zap.String("op", op), zap.Error(err))
+			report.Failed = append(report.Failed, user.GUID)
+		}
+	}
+
+	return report, nil
+}
+
+func (r *RegistrationService) addRandomCredentials(guid string, timeNow *time.Time) error {
+	password := make([]byte, 32)
+	_, err := rand.Read(password)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := r.passwordHasher.Hash(base64.RawURLEncoding.EncodeToString(password))
+	if err != nil {
+		return err
+	}
+
+	return r.postgres.AddUser(&postgresRepo.User{
+		GUID:           guid,
+		HashedPassword: hashedPassword,
+		Algorithm:      r.passwordHasher.Algorithm(),
+		MustChange:     true,
+		CreatedAt:      timeNow,
+	})
+}
+
+// LoginReport lists the users whose logins stop the unique case-insensitive
+// login index from being created, and the logins not stored normalized.
+type LoginReport struct {
+	Conflicts     []*mongoRepo.LoginConflict `json:"conflicts"`
+	NotNormalized []*models.User             `json:"not_normalized"`
+}
+
+// CheckLogins reports the login conflicts to resolve by hand, by renaming or
+// deleting users, before the login index can be created.
+func (r *RegistrationService) CheckLogins() (*LoginReport, error) {
+	conflicts, err := r.mongo.GetLoginConflicts()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := r.mongo.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LoginReport{
+		Conflicts:     conflicts,
+		NotNormalized: []*models.User{},
+	}
+	for _, user := range users {
+		if user.Login != models.NormalizeLogin(user.Login) {
+			report.NotNormalized = append(report.NotNormalized, &models.User{GUID: user.GUID, Login: user.Login})
+		}
+	}
+
+	return report, nil
+}
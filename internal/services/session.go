@@ -0,0 +1,56 @@
+package services
+
+import "PetProjectGo/internal/models"
+
+// ClientInfo describes the device a session is opened from.
+type ClientInfo struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+func (u *UserService) GetSessions(userGuid string, currentSessionGuid string) ([]*models.Session, error) {
+	sessions, err := u.sessions.GetByUserGuid(userGuid)
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		return []*models.Session{}, nil
+	}
+
+	for _, session := range sessions {
+		session.Current = session.GUID == currentSessionGuid
+	}
+
+	return sessions, nil
+}
+
+func (u *UserService) RevokeSession(userGuid string, sessionGuid string) error {
+	err := u.sessions.DeleteByGuid(userGuid, sessionGuid)
+	if err != nil {
+		return err
+	}
+
+	return u.revokeSessionTokens(sessionGuid)
+}
+
+// RevokeOtherSessions ends every session of the user except the current one
+// and returns how many were ended.
+func (u *UserService) RevokeOtherSessions(userGuid string, currentSessionGuid string) (int64, error) {
+	guids, err := u.sessions.DeleteByUserGuid(userGuid, currentSessionGuid)
+	if err != nil {
+		return 0, err
+	}
+
+	err = u.revokeSessionTokens(guids...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(guids)), nil
+}
+
+// ForceLogout ends every session of the user, e.g. on an admin request.
+func (u *UserService) ForceLogout(userGuid string) (int64, error) {
+	return u.RevokeOtherSessions(userGuid, "")
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"PetProjectGo/pkg/tokenGen"
+	"fmt"
+	"time"
+)
+
+const revokedTokenCollection = "revoked_tokens"
+
+var ErrTokenRevoked = fmt.Errorf("token revoked")
+
+// Authenticate verifies an access token and makes sure neither the token
+// nor the session it was issued for has been revoked. API keys are accepted
+// in place of access tokens.
+func (u *UserService) Authenticate(token string) (*tokenGen.UserInfoToken, error) {
+	if IsAPIKey(token) {
+		return u.authenticateAPIKey(token)
+	}
+
+	userInfo, err := u.tokens.VerifyAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{userInfo.TokenID}
+	if userInfo.SessionID != "" {
+		keys = append(keys, userInfo.SessionID)
+	}
+
+	revoked, err := u.denylist.IsRevoked(keys...)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return userInfo, nil
+}
+
+// revokeAccessToken puts a single access token on the denylist until it
+// expires, leeway included.
+func (u *UserService) revokeAccessToken(userInfo *tokenGen.UserInfoToken) error {
+	if userInfo.TokenID == "" {
+		return nil
+	}
+
+	expiresAt := userInfo.ExpiresAt.Add(u.cfg.JWT.Leeway)
+	return u.denylist.Add([]string{userInfo.TokenID}, &expiresAt)
+}
+
+// revokeSessionTokens puts the sessions on the denylist for as long as an
+// access token issued for them may still be valid, leeway included.
+func (u *UserService) revokeSessionTokens(sessionGuids ...string) error {
+	expiresAt := time.Now().Add(u.cfg.TokenExpirationTimeMinutes*time.Minute + u.cfg.JWT.Leeway)
+	return u.denylist.Add(sessionGuids, &expiresAt)
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"PetProjectGo/internal/models"
+	"PetProjectGo/internal/repository/mongoRepo"
+	"PetProjectGo/internal/repository/postgresRepo"
+	"PetProjectGo/pkg/tokenGen"
+	"PetProjectGo/pkg/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const recoveryCodesCount = 10
+
+// totpSkew is the number of time steps a code is accepted before and after
+// the current one, to tolerate clock drift of the phone.
+const totpSkew = 1
+
+var ErrTwoFactorAlreadyEnabled = fmt.Errorf("two-factor authentication already enabled")
+var ErrTwoFactorNotEnabled = fmt.Errorf("two-factor authentication is not enabled")
+var ErrTwoFactorNotEnrolled = fmt.Errorf("two-factor enrollment not started")
+var InvalidTwoFactorCode = fmt.Errorf("invalid two-factor code")
+var InvalidTwoFactorToken = fmt.Errorf("invalid two-factor token")
+var InvalidPassword = fmt.Errorf("invalid password")
+
+// TwoFactorRequiredError is returned by Login when the password is correct
+// but the user has 2FA enabled. Token must be passed to LoginTwoFactor
+// together with a code to get a session.
+type TwoFactorRequiredError struct {
+	Token string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor code required"
+}
+
+// LoginTwoFactor is the second login step. The code is either a TOTP code
+// or one of the recovery codes.
+func (u *UserService) LoginTwoFactor(twoFactorToken string, code string, client *ClientInfo) (string, string, *models.User, error) {
+	const op = "UserService.LoginTwoFactor"
+
+	userGuid, err := u.tokens.VerifyTwoFactorToken(twoFactorToken)
+	if err != nil {
+		u.log.Debug("Invalid two-factor token", zap.String("op", op), zap.Error(err))
+		return "", "", nil, InvalidTwoFactorToken
+	}
+
+	user, err := u.mongo.GetByGuid(userGuid)
+	if errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return "", "", nil, InvalidTwoFactorToken
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// Wrong codes count towards the lockout of the login like wrong passwords.
+	err = u.checkLoginThrottle(user.Login, "")
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	err = u.checkTwoFactorCode(user.GUID, code)
+	if errors.Is(err, InvalidTwoFactorCode) {
+		err2 := u.addLoginFailure(postgresRepo.LoginAttemptKeyLogin, user.Login, u.cfg.Lockout.MaxAttempts)
+		if err2 != nil {
+			return "", "", nil, err2
+		}
+		return "", "", nil, err
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	err = u.loginAttempts.Reset(postgresRepo.LoginAttemptKeyLogin, user.Login)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return u.openSession(user, client)
+}
+
+// EnrollTwoFactor generates a new secret for the user and returns it with the
+// otpauth URI for authenticator apps. 2FA is not in effect until the user
+// confirms a code with ConfirmTwoFactor.
+func (u *UserService) EnrollTwoFactor(userGuid string, login string) (string, string, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	timeNow := time.Now()
+	err = u.twoFactor.SaveTOTP(userGuid, secret, &timeNow)
+	if errors.Is(err, postgresRepo.ErrTOTPAlreadyEnabled) {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URI(u.cfg.TwoFactorIssuer, login, secret), nil
+}
+
+// ConfirmTwoFactor enables 2FA if the code matches the enrolled secret and
+// returns the recovery codes. They are shown only once; just their hashes are kept.
+func (u *UserService) ConfirmTwoFactor(userGuid string, code string) ([]string, error) {
+	const op = "UserService.ConfirmTwoFactor"
+
+	secret, err := u.twoFactor.GetTOTP(userGuid)
+	if errors.Is(err, postgresRepo.ErrTOTPNotFound) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	err = u.checkTOTPCode(secret, code)
+	if err != nil {
+		return nil, err
+	}
+
+	timeNow := time.Now()
+	codes := make([]string, 0, recoveryCodesCount)
+	recoveryCodes := make([]*postgresRepo.RecoveryCode, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		recoveryCodes = append(recoveryCodes, &postgresRepo.RecoveryCode{
+			GUID:      uuid.New().String(),
+			UserGUID:  userGuid,
+			CodeHash:  tokenGen.HashToken(normalizeRecoveryCode(code)),
+			CreatedAt: &timeNow,
+		})
+	}
+
+	err = u.twoFactor.EnableTOTP(userGuid, recoveryCodes, &timeNow)
+	if errors.Is(err, postgresRepo.ErrTOTPNotFound) {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Two-factor authentication enabled", zap.String("op", op), zap.String("guid", userGuid))
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off. The user has to prove both factors again.
+func (u *UserService) DisableTwoFactor(userGuid string, password string, code string) error {
+	const op = "UserService.DisableTwoFactor"
+
+	hashedPassword, err := u.postgres.GetHashPasswordByGuid(userGuid)
+	if err != nil {
+		return err
+	}
+	if !u.checkHashPassword(password, hashedPassword) {
+		return InvalidPassword
+	}
+
+	err = u.checkTwoFactorCode(userGuid, code)
+	if err != nil {
+		return err
+	}
+
+	err = u.twoFactor.DeleteTOTP(userGuid)
+	if err != nil {
+		return err
+	}
+
+	u.log.Info("Two-factor authentication disabled", zap.String("op", op), zap.String("guid", userGuid))
+
+	return nil
+}
+
+func (u *UserService) twoFactorEnabled(userGuid string) (bool, error) {
+	secret, err := u.twoFactor.GetTOTP(userGuid)
+	if errors.Is(err, postgresRepo.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return secret.EnabledAt != nil, nil
+}
+
+// checkTwoFactorCode accepts a TOTP code or an unused recovery code of a user
+// with 2FA enabled. Both kinds of codes work only once.
+func (u *UserService) checkTwoFactorCode(userGuid string, code string) error {
+	const op = "UserService.checkTwoFactorCode"
+
+	secret, err := u.twoFactor.GetTOTP(userGuid)
+	if errors.Is(err, postgresRepo.ErrTOTPNotFound) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if secret.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if len(code) == totp.Digits {
+		return u.checkTOTPCode(secret, code)
+	}
+
+	timeNow := time.Now()
+	ok, err := u.twoFactor.ConsumeRecoveryCode(userGuid, tokenGen.HashToken(normalizeRecoveryCode(code)), &timeNow)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return InvalidTwoFactorCode
+	}
+
+	u.log.Info("Recovery code used", zap.String("op", op), zap.String("guid", userGuid))
+
+	return nil
+}
+
+func (u *UserService) checkTOTPCode(secret *postgresRepo.TOTP, code string) error {
+	step, ok := totp.Validate(secret.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return InvalidTwoFactorCode
+	}
+
+	ok, err := u.twoFactor.UseStep(secret.UserGUID, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return InvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// generateRecoveryCode returns a random code like "k3j7a-x7q2m".
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+	return code[:5] + "-" + code[5:10], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
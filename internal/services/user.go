@@ -6,6 +6,9 @@ import (
 	"PetProjectGo/internal/repository/mongoRepo"
 	"PetProjectGo/internal/repository/postgresRepo"
 	"PetProjectGo/pkg/logging"
+	"PetProjectGo/pkg/mail"
+	"PetProjectGo/pkg/passwordHash"
+	"PetProjectGo/pkg/passwordPolicy"
 	"PetProjectGo/pkg/storage/mongodb"
 	"PetProjectGo/pkg/tokenGen"
 	"fmt"
@@ -14,31 +17,54 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 	"time"
 )
 
 const userCollection = "users"
+const sessionCollection = "sessions"
 
 var ErrUserAlreadyExists = fmt.Errorf("user already exists")
 var InvalidLoginPassword = fmt.Errorf("invalid login or password")
-var RefreshTokenExpiredError = fmt.Errorf("refresh token expired")
-var UserIsLogged = fmt.Errorf("user is logged")
+var InvalidRefreshToken = fmt.Errorf("invalid refresh token")
+var RefreshTokenExpired = fmt.Errorf("refresh token expired")
+var RefreshTokenReused = fmt.Errorf("refresh token reused, session revoked")
 var UserIsUnLogged = fmt.Errorf("user is unlogged")
 var Unauthorized = fmt.Errorf("unauthorized")
+var ErrInvalidRole = fmt.Errorf("invalid role")
+var ErrInvalidLogin = fmt.Errorf(
+	"login must be %d to %d characters long, without spaces", models.LoginMinLength, models.LoginMaxLength,
+)
+var ErrEmailNotVerified = fmt.Errorf("email is not verified")
+var ErrPasswordChangeRequired = fmt.Errorf("password must be changed, reset it to log in")
 
 type NewUserM struct {
 	Login    string `json:"login"`
+	Email    string `json:"email"`
 	Password string `json:"password"`
 	Name     string `json:"name"`
 	LastName string `json:"last_name"`
+	Role     string `json:"-"`
+
+	EmailVerified bool `json:"-"`
 }
 
 type UserService struct {
-	log      *logging.Logger
-	cfg      *config.AppConfig
-	mongo    *mongoRepo.UserRepoM
-	postgres *postgresRepo.UserRepoP
+	log           *logging.Logger
+	cfg           *config.AppConfig
+	mongo         *mongoRepo.UserRepoM
+	sessions      *mongoRepo.SessionRepoM
+	denylist      *mongoRepo.RevokedTokenRepoM
+	postgres      *postgresRepo.UserRepoP
+	userTokens    *postgresRepo.UserTokenRepoP
+	twoFactor     *postgresRepo.TwoFactorRepoP
+	loginAttempts *postgresRepo.LoginAttemptRepoP
+	apiKeys       *postgresRepo.APIKeyRepoP
+	registrations *RegistrationService
+	tokens        *tokenGen.Generator
+	mailer        mail.Sender
+
+	passwordPolicy *passwordPolicy.Policy
+	passwordHasher *passwordHash.Hasher
 }
 
 func NewUserService(
@@ -46,15 +72,74 @@ func NewUserService(
 	cfg *config.AppConfig,
 	mongo *mongodb.MongoDB,
 	postgres *sqlx.DB,
-) *UserService {
+	tokens *tokenGen.Generator,
+	mailer mail.Sender,
+) (*UserService, error) {
+	hasher, err := newPasswordHasher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	registrations, err := NewRegistrationService(log, cfg, mongo, postgres)
+	if err != nil {
+		return nil, err
+	}
+
 	mongoDb := mongoRepo.NewUserRepoM(log, mongo, userCollection)
+	err = mongoDb.CreateIndexesUser()
+	if err != nil {
+		// Existing logins that differ only in case prevent the unique index;
+		// the server still starts so they can be resolved.
+		log.Error("User indexes not created, run the check-logins command", zap.Error(err))
+	}
+	sessionsDb := mongoRepo.NewSessionRepoM(log, mongo, sessionCollection)
+	err = sessionsDb.CreateIndexesSession()
+	if err != nil {
+		return nil, err
+	}
+	denylistDb := mongoRepo.NewRevokedTokenRepoM(log, mongo, revokedTokenCollection)
+	err = denylistDb.CreateIndexesRevokedToken()
+	if err != nil {
+		return nil, err
+	}
 	postgresDb := postgresRepo.NewUserRepoP(log, postgres)
+	userTokensDb := postgresRepo.NewUserTokenRepoP(log, postgres)
+	twoFactorDb := postgresRepo.NewTwoFactorRepoP(log, postgres)
+	loginAttemptsDb := postgresRepo.NewLoginAttemptRepoP(log, postgres)
+	apiKeysDb := postgresRepo.NewAPIKeyRepoP(log, postgres)
 	return &UserService{
-		log:      log,
-		cfg:      cfg,
-		mongo:    mongoDb,
-		postgres: postgresDb,
-	}
+		log:           log,
+		cfg:           cfg,
+		mongo:         mongoDb,
+		sessions:      sessionsDb,
+		denylist:      denylistDb,
+		postgres:      postgresDb,
+		userTokens:    userTokensDb,
+		twoFactor:     twoFactorDb,
+		loginAttempts: loginAttemptsDb,
+		apiKeys:       apiKeysDb,
+		registrations: registrations,
+		tokens:        tokens,
+		mailer:        mailer,
+
+		passwordPolicy: newPasswordPolicy(cfg),
+		passwordHasher: hasher,
+	}, nil
+}
+
+func newPasswordHasher(cfg *config.AppConfig) (*passwordHash.Hasher, error) {
+	return passwordHash.NewHasher(passwordHash.Params{
+		Algorithm:         cfg.PasswordHash.Algorithm,
+		BcryptCost:        cfg.PasswordHash.BcryptCost,
+		Argon2Memory:      cfg.PasswordHash.Argon2MemoryKiB,
+		Argon2Iterations:  cfg.PasswordHash.Argon2Iterations,
+		Argon2Parallelism: cfg.PasswordHash.Argon2Parallelism,
+	})
+}
+
+// RunRegistrationOutbox runs the outbox worker of the registrations. It
+// blocks, so run it in a goroutine.
+func (u *UserService) RunRegistrationOutbox() {
+	u.registrations.RunOutbox()
 }
 
 func (u *UserService) GetAllUsers() ([]*models.User, error) {
@@ -65,125 +150,233 @@ func (u *UserService) GetAllUsers() ([]*models.User, error) {
 	return users, nil
 }
 
-func (u *UserService) GetMeInfo(token string) (string, *tokenGen.UserInfoToken, error) {
-	userInfoToken, ok := tokenGen.VerifyToken(u.cfg.SecretKeyToken, token)
-	if userInfoToken == nil {
-		return "", nil, Unauthorized
-	}
-	user, _ := u.mongo.GetByGuid(userInfoToken.ID)
-	if !user.IsLogged {
-		return "", nil, UserIsUnLogged
+// GetMeInfo returns the owner of an access token already checked by
+// Authenticate, as long as the session it was issued for still exists. It
+// never issues tokens: an expired access token is renewed with Refresh.
+func (u *UserService) GetMeInfo(userInfo *tokenGen.UserInfoToken) (*tokenGen.UserInfoToken, error) {
+	if userInfo.APIKeyID != "" {
+		return userInfo, nil
 	}
 
-	if !ok {
-		return u.checkTokenExpired(user)
+	_, err := u.sessions.GetByGuid(userInfo.SessionID)
+	if errors.Is(err, mongoRepo.ErrSessionNotFound) {
+		return nil, UserIsUnLogged
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return token, userInfoToken, nil
+	return userInfo, nil
 }
 
-func (u *UserService) UnLogin(guid string) error {
-	user, err := u.mongo.GetByGuid(guid)
-	if errors.Is(err, mongoRepo.ErrUserNotFound) {
-		return InvalidLoginPassword
-	}
+// UnLogin ends the session the access token was issued for. The access
+// token itself stops being accepted immediately.
+func (u *UserService) UnLogin(userInfo *tokenGen.UserInfoToken) error {
+	err := u.revokeAccessToken(userInfo)
 	if err != nil {
 		return err
 	}
 
-	if !user.IsLogged {
+	err = u.sessions.DeleteByGuid(userInfo.ID, userInfo.SessionID)
+	if errors.Is(err, mongoRepo.ErrSessionNotFound) {
 		return UserIsUnLogged
 	}
-
-	timeNow := time.Now()
-	err = u.mongo.UpdateUnLoggingUser(guid, &timeNow)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return u.revokeSessionTokens(userInfo.SessionID)
 }
 
-func (u *UserService) Login(login string, password string) (string, *models.User, error) {
+// Login checks the credentials and opens a new session for the client.
+// A user may have any number of sessions at the same time.
+func (u *UserService) Login(login string, password string, client *ClientInfo) (string, string, *models.User, error) {
+	err := u.checkLoginThrottle(login, client.IP)
+	if err != nil {
+		return "", "", nil, err
+	}
+
 	user, err := u.mongo.GetByLogin(login)
 	if errors.Is(err, mongoRepo.ErrUserNotFound) {
-		return "", nil, InvalidLoginPassword
+		return "", "", nil, u.loginFailed(login, client.IP)
 	}
 	if err != nil {
-		return "", nil, err
-	}
-
-	_, ok := tokenGen.VerifyToken(u.cfg.SecretKeyToken, user.RefreshToken)
-	if ok {
-		if user.IsLogged {
-			return "", nil, UserIsLogged
-		}
+		return "", "", nil, err
 	}
 
 	hashedPassword, err := u.postgres.GetHashPasswordByGuid(user.GUID)
 	if err != nil {
-		return "", nil, InvalidLoginPassword
+		return "", "", nil, InvalidLoginPassword
 	}
 
-	ok = u.checkHashPassword(password, hashedPassword)
+	ok := u.checkHashPassword(password, hashedPassword)
 	if !ok {
-		return "", nil, InvalidLoginPassword
+		return "", "", nil, u.loginFailed(login, client.IP)
+	}
+	u.rehashPassword(user.GUID, password, hashedPassword)
+
+	// A password to change, like the random ones given by repair, only
+	// opens the way to a password reset.
+	credentials, err := u.postgres.GetCredentials(user.GUID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if credentials.MustChange {
+		return "", "", nil, ErrPasswordChangeRequired
+	}
+
+	// Users without an email, registered before emails were asked for, have
+	// nothing to verify and no way to add one, so they are let through.
+	if u.cfg.EmailVerificationRequired && user.Email != "" && user.EmailVerifiedAt == nil {
+		return "", "", nil, ErrEmailNotVerified
+	}
+
+	enabled, err := u.twoFactorEnabled(user.GUID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if enabled {
+		twoFactorToken, err := u.tokens.NewTwoFactorToken(time.Now().Add(u.cfg.TwoFactorTokenTTL), user.GUID)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return "", "", nil, &TwoFactorRequiredError{Token: twoFactorToken}
+	}
+
+	err = u.loginAttempts.Reset(postgresRepo.LoginAttemptKeyLogin, login)
+	if err != nil {
+		return "", "", nil, err
 	}
 
-	t, rt, err := u.generateTokens(user)
+	return u.openSession(user, client)
+}
 
+// openSession starts a session for a user whose credentials were checked.
+func (u *UserService) openSession(user *models.User, client *ClientInfo) (string, string, *models.User, error) {
 	timeNow := time.Now()
-	err = u.mongo.UpdatedLoggingUser(user.GUID, rt, &timeNow)
+	session := &models.Session{
+		GUID:       uuid.New().String(),
+		UserGuid:   user.GUID,
+		Device:     client.Device,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  &timeNow,
+		LastUsedAt: &timeNow,
+	}
+
+	t, rt, rtExpiresAt, err := u.generateTokens(user, session.GUID)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
+	}
+
+	session.RefreshTokenHash = tokenGen.HashToken(rt)
+	session.ExpiresAt = &rtExpiresAt
+
+	err = u.sessions.AddSession(session)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	err = u.mongo.UpdatedLoggingUser(user.GUID, &timeNow)
+	if err != nil {
+		return "", "", nil, err
 	}
 
 	user.LastLoginAt = &timeNow
-	user.RefreshToken = rt
-	user.IsLogged = true
 
-	return t, user, nil
+	return t, rt, user, nil
 }
 
-func (u *UserService) Refresh(guid string) (string, *models.User, error) {
-	user, err := u.mongo.GetByGuid(guid)
-	if user == nil {
-		return "", nil, mongoRepo.ErrUserNotFound
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// The presented token is single-use: replaying an already rotated token
+// revokes its session, logging out both the attacker and the victim.
+func (u *UserService) Refresh(refreshToken string) (string, string, *models.User, error) {
+	const op = "UserService.Refresh"
+
+	rtInfo, err := u.tokens.VerifyRefreshToken(refreshToken)
+	if errors.Is(err, tokenGen.ErrTokenExpired) {
+		return "", "", nil, RefreshTokenExpired
 	}
 	if err != nil {
-		if !errors.Is(err, mongoRepo.ErrUserNotFound) {
-			return "", nil, err
-		}
+		u.log.Debug("Invalid refresh token", zap.String("op", op), zap.Error(err))
+		return "", "", nil, InvalidRefreshToken
 	}
 
-	_, ok := tokenGen.VerifyToken(u.cfg.SecretKeyToken, user.RefreshToken)
-	if !ok {
-		return "", nil, RefreshTokenExpiredError
+	session, err := u.sessions.GetByGuid(rtInfo.Family)
+	if errors.Is(err, mongoRepo.ErrSessionNotFound) {
+		return "", "", nil, InvalidRefreshToken
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+	if session.UserGuid != rtInfo.UserGUID {
+		return "", "", nil, InvalidRefreshToken
 	}
 
-	var newInfoToken *tokenGen.UserInfoToken
-	err = mapstructure.Decode(user, &newInfoToken)
+	oldHash := tokenGen.HashToken(refreshToken)
+	if session.RefreshTokenHash != oldHash {
+		return "", "", nil, u.revokeReusedSession(op, session)
+	}
+
+	user, err := u.mongo.GetByGuid(session.UserGuid)
+	if errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return "", "", nil, InvalidRefreshToken
+	}
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
 
-	timeTExpired := time.Now().Add(u.cfg.TokenExpirationTimeMinutes * time.Minute)
-	t, err := tokenGen.NewToken(u.cfg.SecretKeyToken, timeTExpired, newInfoToken)
+	t, rt, rtExpiresAt, err := u.generateTokens(user, session.GUID)
+	if err != nil {
+		return "", "", nil, err
+	}
 
 	timeNow := time.Now()
-	err = u.mongo.UpdatedLoggingUser(user.GUID, user.RefreshToken, &timeNow)
+	rotated, err := u.sessions.RotateRefreshToken(session.GUID, oldHash, tokenGen.HashToken(rt), &rtExpiresAt, &timeNow)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
+	}
+	if !rotated {
+		return "", "", nil, u.revokeReusedSession(op, session)
 	}
 
-	user.LastLoginAt = &timeNow
+	return t, rt, user, nil
+}
+
+func (u *UserService) revokeReusedSession(op string, session *models.Session) error {
+	u.log.Warn(
+		"Refresh token reuse detected, revoking session",
+		zap.String("op", op),
+		zap.String("guid", session.UserGuid),
+		zap.String("session", session.GUID),
+	)
+
+	err := u.sessions.DeleteByGuid(session.UserGuid, session.GUID)
+	if err != nil && !errors.Is(err, mongoRepo.ErrSessionNotFound) {
+		return err
+	}
 
-	return t, user, nil
+	err = u.revokeSessionTokens(session.GUID)
+	if err != nil {
+		return err
+	}
+
+	return RefreshTokenReused
 }
 
 func (u *UserService) Register(nur *NewUserM) (*models.User, error) {
 	const op = "UserService.Register"
 
+	nur.Login = models.NormalizeLogin(nur.Login)
+	if !models.IsValidLogin(nur.Login) {
+		return nil, ErrInvalidLogin
+	}
+
+	err := u.checkPassword(nur.Password, nur.Login)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := u.mongo.GetByLogin(nur.Login)
 	if user != nil {
 		u.log.Info("User already exists", zap.String("op", op), zap.Error(ErrUserAlreadyExists))
@@ -195,19 +388,33 @@ func (u *UserService) Register(nur *NewUserM) (*models.User, error) {
 		}
 	}
 
+	pending, err := u.registrations.IsLoginPending(nur.Login)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		u.log.Info("User already exists", zap.String("op", op), zap.Error(ErrUserAlreadyExists))
+		return nil, ErrUserAlreadyExists
+	}
+
+	role := nur.Role
+	if role == "" {
+		role = models.RoleCustomer
+	}
+
 	timeNow := time.Now()
 	userGuid := uuid.New().String()
 	newUser := &models.User{
 		GUID:      userGuid,
 		Login:     nur.Login,
+		Email:     nur.Email,
 		Name:      nur.Name,
 		LastName:  nur.LastName,
+		Role:      role,
 		CreatedAt: &timeNow,
 	}
-
-	err = u.mongo.AddUser(newUser)
-	if err != nil {
-		return nil, err
+	if nur.EmailVerified {
+		newUser.EmailVerifiedAt = &timeNow
 	}
 
 	hashedPassword, err := u.HashPassword(nur.Password)
@@ -217,38 +424,159 @@ func (u *UserService) Register(nur *NewUserM) (*models.User, error) {
 	newUserP := postgresRepo.User{
 		GUID:           userGuid,
 		HashedPassword: hashedPassword,
+		Algorithm:      u.passwordHasher.Algorithm(),
 		CreatedAt:      &timeNow,
 	}
 
-	err = u.postgres.AddUser(&newUserP)
+	// The user is registered once it is in Postgres; adding it to Mongo is
+	// retried by the outbox worker if it fails here.
+	applied, err := u.registrations.Register(newUser, &newUserP)
 	if err != nil {
-		err2 := u.mongo.DeleteByGuid(userGuid)
-		if err2 != nil {
-			u.log.Error("Error deleting user", zap.String("op", op), zap.Error(err))
-			return nil, err2
-		}
 		return nil, err
 	}
-	return newUser, err
+
+	if applied && newUser.EmailVerifiedAt == nil && newUser.Email != "" {
+		// The user can ask for another message, so registration does not fail here.
+		err = u.sendEmailVerification(newUser)
+		if err != nil {
+			u.log.Error("Error sending email verification", zap.String("op", op), zap.Error(err))
+		}
+	}
+
+	return newUser, nil
+}
+
+func (u *UserService) SetRole(guid string, role string) error {
+	const op = "UserService.SetRole"
+
+	if !models.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	user, err := u.mongo.GetByGuid(guid)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	timeNow := time.Now()
+	err = u.mongo.UpdateRole(guid, role, &timeNow)
+	if err != nil {
+		return err
+	}
+
+	// The access tokens carry the role, so the sessions are ended for the
+	// new role to take effect right away.
+	revoked, err := u.ForceLogout(guid)
+	if err != nil {
+		return err
+	}
+	u.log.Info("Sessions ended on role change", zap.String("op", op), zap.String("guid", guid), zap.Int64("sessions", revoked))
+
+	return nil
+}
+
+// BootstrapAdmin makes sure there is at least one admin. If none exists, the
+// user from the app.admin config section is promoted, or registered when
+// missing. Nothing is done when app.admin.login is not set.
+func (u *UserService) BootstrapAdmin() error {
+	const op = "UserService.BootstrapAdmin"
+
+	if u.cfg.Admin.Login == "" {
+		return nil
+	}
+
+	count, err := u.mongo.CountByRole(models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	user, err := u.mongo.GetByLogin(u.cfg.Admin.Login)
+	if err != nil && !errors.Is(err, mongoRepo.ErrUserNotFound) {
+		return err
+	}
+
+	if user != nil {
+		err = u.SetRole(user.GUID, models.RoleAdmin)
+		if err != nil {
+			return err
+		}
+		u.log.Info("User promoted to admin", zap.String("op", op), zap.String("login", user.Login))
+		return nil
+	}
+
+	if u.cfg.Admin.Password == "" {
+		return fmt.Errorf("%s: admin password is not set", op)
+	}
+
+	_, err = u.Register(&NewUserM{
+		Login:    u.cfg.Admin.Login,
+		Password: u.cfg.Admin.Password,
+		Role:     models.RoleAdmin,
+		// There is no address to confirm for the configured admin.
+		EmailVerified: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	u.log.Info("Admin user created", zap.String("op", op), zap.String("login", u.cfg.Admin.Login))
+
+	return nil
 }
 
 func (u *UserService) checkHashPassword(password string, hashedPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+	const op = "UserService.checkHashPassword"
+
+	ok, err := u.passwordHasher.Verify(password, hashedPassword)
+	if err != nil {
+		u.log.Error("Error verifying password", zap.String("op", op), zap.Error(err))
+		return false
+	}
+	return ok
+}
+
+// rehashPassword replaces a hash made with an outdated algorithm or
+// parameters once the user proved the password. Failing to do so is not
+// an error for the caller: the old hash still works.
+func (u *UserService) rehashPassword(guid string, password string, hashedPassword string) {
+	const op = "UserService.rehashPassword"
+
+	if !u.passwordHasher.NeedsRehash(hashedPassword) {
+		return
+	}
+
+	newHashedPassword, err := u.HashPassword(password)
+	if err != nil {
+		return
+	}
+
+	err = u.postgres.RehashPassword(guid, hashedPassword, newHashedPassword, u.passwordHasher.Algorithm())
+	if err != nil {
+		u.log.Error("Error rehashing password", zap.String("op", op), zap.Error(err))
+		return
+	}
+
+	u.log.Info("Password rehashed", zap.String("op", op), zap.String("guid", guid), zap.String("algorithm", u.passwordHasher.Algorithm()))
 }
 
 func (u *UserService) HashPassword(password string) (string, error) {
 	const op = "UserService.HashPassword"
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	hashed, err := u.passwordHasher.Hash(password)
 	if err != nil {
 		u.log.Error("Error hashing password", zap.String("op", op), zap.Error(err))
 		return "", err
 	}
-	return string(hashed), nil
+	return hashed, nil
 }
 
-func (u *UserService) generateTokens(user *models.User) (string, string, error) {
+func (u *UserService) generateTokens(user *models.User, sessionGuid string) (string, string, time.Time, error) {
 	var newInfoToken *tokenGen.UserInfoToken
 
 	timeTExpired := time.Now().Add(u.cfg.TokenExpirationTimeMinutes * time.Minute)
@@ -256,47 +584,19 @@ func (u *UserService) generateTokens(user *models.User) (string, string, error)
 
 	err := mapstructure.Decode(user, &newInfoToken)
 	if err != nil {
-		return "", "", err
+		return "", "", time.Time{}, err
 	}
+	newInfoToken.SessionID = sessionGuid
 
-	t, err := tokenGen.NewToken(u.cfg.SecretKeyToken, timeTExpired, newInfoToken)
+	t, err := u.tokens.NewAccessToken(timeTExpired, newInfoToken)
 	if err != nil {
-		return "", "", err
+		return "", "", time.Time{}, err
 	}
 
-	rt, err := tokenGen.NewToken(u.cfg.SecretKeyToken, timeRtExpired, nil)
+	rt, err := u.tokens.NewRefreshToken(timeRtExpired, user.GUID, sessionGuid)
 	if err != nil {
-		return "", "", err
-	}
-
-	return t, rt, nil
-}
-
-func (u *UserService) checkTokenExpired(user *models.User) (string, *tokenGen.UserInfoToken, error) {
-	const op = "UserService.checkTokenExpired"
-
-	var NewUserInfoToken *tokenGen.UserInfoToken
-	err := mapstructure.Decode(user, &NewUserInfoToken)
-	if err != nil {
-		u.log.Error("can't decode user", zap.String("op", op), zap.Error(err))
-		return "", nil, err
-	}
-
-	t, _, err := u.Refresh(user.GUID)
-	if err != nil {
-		if errors.Is(err, RefreshTokenExpiredError) {
-			hashedPassword, errGetPassword := u.postgres.GetHashPasswordByGuid(user.GUID)
-			if errGetPassword != nil {
-				return "", nil, errGetPassword
-			}
-			newT, _, errReLogin := u.Login(user.Login, hashedPassword)
-			if errReLogin != nil {
-				return "", nil, errReLogin
-			}
-			return newT, NewUserInfoToken, nil
-		}
-		return "", nil, err
+		return "", "", time.Time{}, err
 	}
 
-	return t, NewUserInfoToken, nil
+	return t, rt, timeRtExpired, nil
 }
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSender writes every message into the outbox directory as an .eml
+// file instead of sending it. It is meant for local development.
+type FileSender struct {
+	from      string
+	outboxDir string
+}
+
+func NewFileSender(from string, outboxDir string) (*FileSender, error) {
+	err := os.MkdirAll(outboxDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSender{
+		from:      from,
+		outboxDir: outboxDir,
+	}, nil
+}
+
+func (f *FileSender) Send(msg *Message) error {
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	return os.WriteFile(filepath.Join(f.outboxDir, name), buildMessage(f.from, msg), 0600)
+}
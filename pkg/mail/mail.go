@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"PetProjectGo/internal/config"
+	"fmt"
+)
+
+const (
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+var ErrUnknownDriver = fmt.Errorf("unknown mail driver")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages to users.
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// NewSender builds the sender selected by mail.driver.
+func NewSender(cfg *config.MailConfig) (Sender, error) {
+	switch cfg.Driver {
+	case DriverFile:
+		return NewFileSender(cfg.From, cfg.OutboxDir)
+	case DriverSMTP:
+		return NewSMTPSender(cfg.From, &cfg.SMTP), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, cfg.Driver)
+	}
+}
+
+func buildMessage(from string, msg *Message) []byte {
+	return []byte(fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"UTF-8\"\r\n\r\n%s\r\n",
+		from, msg.To, msg.Subject, msg.Body,
+	))
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"PetProjectGo/internal/config"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type SMTPSender struct {
+	from string
+	cfg  *config.SMTPConfig
+}
+
+func NewSMTPSender(from string, cfg *config.SMTPConfig) *SMTPSender {
+	return &SMTPSender{
+		from: from,
+		cfg:  cfg,
+	}
+}
+
+// Send delivers the message like smtp.SendMail does, but gives up after
+// smtp.timeout instead of waiting on a stalled server forever.
+func (s *SMTPSender) Send(msg *Message) error {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	conn, err := net.DialTimeout("tcp", addr, s.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if s.cfg.Timeout > 0 {
+		err = conn.SetDeadline(time.Now().Add(s.cfg.Timeout))
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.cfg.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(s.from)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(msg.To)
+	if err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buildMessage(s.from, msg))
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,190 @@
+package passwordHash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	AlgBcrypt   = "bcrypt"
+	AlgArgon2id = "argon2id"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrUnsupportedAlgorithm = fmt.Errorf("unsupported password hash algorithm")
+var ErrInvalidHash = fmt.Errorf("invalid password hash")
+
+type Params struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// Hasher hashes passwords with the configured algorithm and verifies hashes
+// made with any supported algorithm. Hashes are self-describing: bcrypt
+// hashes use the usual $2a$ format and argon2id hashes the PHC string format
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+type Hasher struct {
+	params Params
+}
+
+func NewHasher(params Params) (*Hasher, error) {
+	switch params.Algorithm {
+	case AlgBcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost %d is out of range", params.BcryptCost)
+		}
+	case AlgArgon2id:
+		if params.Argon2Memory == 0 || params.Argon2Iterations == 0 || params.Argon2Parallelism == 0 {
+			return nil, fmt.Errorf("argon2id memory, iterations and parallelism must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, params.Algorithm)
+	}
+
+	return &Hasher{params: params}, nil
+}
+
+func (h *Hasher) Algorithm() string {
+	return h.params.Algorithm
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == AlgBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeArgon2id(argon2Hash{
+		memory:      h.params.Argon2Memory,
+		iterations:  h.params.Argon2Iterations,
+		parallelism: h.params.Argon2Parallelism,
+		salt:        salt,
+		key: argon2.IDKey(
+			[]byte(password), salt,
+			h.params.Argon2Iterations, h.params.Argon2Memory, h.params.Argon2Parallelism,
+			argon2KeyLength,
+		),
+	}), nil
+}
+
+// Verify reports whether the password matches the encoded hash.
+func (h *Hasher) Verify(password string, encoded string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	hash, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), hash.salt, hash.iterations, hash.memory, hash.parallelism, uint32(len(hash.key)))
+	return subtle.ConstantTimeCompare(key, hash.key) == 1, nil
+}
+
+// NeedsRehash reports whether the hash was made with another algorithm or
+// other parameters than the hasher uses now.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if isBcrypt(encoded) {
+		if h.params.Algorithm != AlgBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.params.BcryptCost
+	}
+
+	if h.params.Algorithm != AlgArgon2id {
+		return true
+	}
+	hash, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return hash.memory != h.params.Argon2Memory ||
+		hash.iterations != h.params.Argon2Iterations ||
+		hash.parallelism != h.params.Argon2Parallelism
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func encodeArgon2id(hash argon2Hash) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, hash.memory, hash.iterations, hash.parallelism,
+		base64.RawStdEncoding.EncodeToString(hash.salt),
+		base64.RawStdEncoding.EncodeToString(hash.key),
+	)
+}
+
+func decodeArgon2id(encoded string) (argon2Hash, error) {
+	var hash argon2Hash
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return hash, ErrInvalidHash
+	}
+	if parts[1] != AlgArgon2id {
+		return hash, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, parts[1])
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return hash, ErrInvalidHash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.iterations, &hash.parallelism)
+	if err != nil {
+		return hash, ErrInvalidHash
+	}
+
+	hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return hash, ErrInvalidHash
+	}
+	hash.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash.key) == 0 {
+		return hash, ErrInvalidHash
+	}
+
+	return hash, nil
+}
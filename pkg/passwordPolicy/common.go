@@ -0,0 +1,33 @@
+package passwordPolicy
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+//go:embed common.txt
+var commonList string
+
+var (
+	commonOnce sync.Once
+	common     map[string]struct{}
+)
+
+// IsCommon reports whether the password, ignoring case, is in the bundled
+// list of frequently used passwords.
+func IsCommon(password string) bool {
+	commonOnce.Do(func() {
+		lines := strings.Split(commonList, "\n")
+		common = make(map[string]struct{}, len(lines))
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				common[strings.ToLower(line)] = struct{}{}
+			}
+		}
+	})
+
+	_, ok := common[strings.ToLower(password)]
+	return ok
+}
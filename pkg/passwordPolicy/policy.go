@@ -0,0 +1,91 @@
+package passwordPolicy
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BcryptMaxLength is the number of bytes bcrypt takes into account; the
+// rest of a longer password is silently ignored.
+const BcryptMaxLength = 72
+
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "upper_case"
+	RuleLower     = "lower_case"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleLogin     = "equals_login"
+	RuleCommon    = "common_password"
+	RuleReused    = "recently_used"
+)
+
+// Violation is a rule the password breaks. Param holds the limit of the
+// rule, e.g. the minimal length.
+type Violation struct {
+	Rule  string
+	Param string
+}
+
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	RejectLogin   bool
+	RejectCommon  bool
+}
+
+// Check returns every rule the password breaks, or nil if it is acceptable.
+// The login may be empty when it is not known.
+func (p *Policy) Check(password string, login string) []Violation {
+	var violations []Violation
+
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, Violation{Rule: RuleMinLength, Param: strconv.Itoa(p.MinLength)})
+	}
+	// The limit is in bytes since that is what bcrypt counts.
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, Violation{Rule: RuleMaxLength, Param: strconv.Itoa(p.MaxLength)})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{Rule: RuleUpper})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{Rule: RuleLower})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{Rule: RuleDigit})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{Rule: RuleSymbol})
+	}
+
+	if p.RejectLogin && login != "" && strings.EqualFold(password, login) {
+		violations = append(violations, Violation{Rule: RuleLogin})
+	}
+	if p.RejectCommon && IsCommon(password) {
+		violations = append(violations, Violation{Rule: RuleCommon})
+	}
+
+	return violations
+}
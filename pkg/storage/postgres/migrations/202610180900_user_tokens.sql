@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_tokens (
+    guid VARCHAR(36) NOT NULL,
+    user_guid VARCHAR(36) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(guid)
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_user_guid_purpose_idx ON user_tokens (user_guid, purpose);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_tokens;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_guid VARCHAR(36) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    last_used_step BIGINT NULL,
+    enabled_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(user_guid)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    guid VARCHAR(36) NOT NULL,
+    user_guid VARCHAR(36) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(guid)
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_guid_idx ON recovery_codes (user_guid);
+
+-- +goose Down
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
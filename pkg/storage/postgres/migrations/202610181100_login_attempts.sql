@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key_type VARCHAR(16) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP NULL,
+    PRIMARY KEY(key_type, key)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS credentials (
+    user_guid VARCHAR(36) NOT NULL,
+    hashed_password VARCHAR(255) NOT NULL,
+    algorithm VARCHAR(16) NOT NULL,
+    must_change BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(user_guid)
+);
+
+INSERT INTO credentials (user_guid, hashed_password, algorithm, created_at, updated_at)
+SELECT guid,
+       hashed_password,
+       CASE WHEN hashed_password LIKE '$argon2id$%' THEN 'argon2id' ELSE 'bcrypt' END,
+       created_at,
+       created_at
+FROM passwords
+ON CONFLICT (user_guid) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS password_history (
+    guid VARCHAR(36) NOT NULL,
+    user_guid VARCHAR(36) NOT NULL,
+    hashed_password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(guid)
+);
+
+CREATE INDEX IF NOT EXISTS password_history_user_guid_created_at_idx ON password_history (user_guid, created_at);
+
+DROP TABLE IF EXISTS passwords;
+
+-- +goose Down
+CREATE TABLE IF NOT EXISTS passwords (
+    guid VARCHAR(36) NOT NULL,
+    hashed_password VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(guid)
+);
+
+INSERT INTO passwords (guid, hashed_password, created_at)
+SELECT user_guid, hashed_password, created_at FROM credentials
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS password_history;
+DROP TABLE IF EXISTS credentials;
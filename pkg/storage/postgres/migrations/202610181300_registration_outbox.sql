@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS registration_outbox (
+    user_guid VARCHAR(36) NOT NULL,
+    login VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    processed_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(user_guid)
+);
+
+CREATE INDEX IF NOT EXISTS registration_outbox_pending_idx ON registration_outbox (created_at) WHERE processed_at IS NULL;
+CREATE INDEX IF NOT EXISTS registration_outbox_login_idx ON registration_outbox (login) WHERE processed_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS registration_outbox;
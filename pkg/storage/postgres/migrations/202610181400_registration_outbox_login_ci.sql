@@ -0,0 +1,7 @@
+-- +goose Up
+DROP INDEX IF EXISTS registration_outbox_login_idx;
+CREATE INDEX IF NOT EXISTS registration_outbox_login_idx ON registration_outbox (lower(login)) WHERE processed_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS registration_outbox_login_idx;
+CREATE INDEX IF NOT EXISTS registration_outbox_login_idx ON registration_outbox (login) WHERE processed_at IS NULL;
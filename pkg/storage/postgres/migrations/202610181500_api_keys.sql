@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS api_keys (
+    guid VARCHAR(36) NOT NULL,
+    user_guid VARCHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(32) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(guid)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_keys_prefix_idx ON api_keys (prefix);
+CREATE INDEX IF NOT EXISTS api_keys_user_guid_idx ON api_keys (user_guid);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
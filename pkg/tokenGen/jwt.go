@@ -1,72 +1,224 @@
 package tokenGen
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"time"
 )
 
+const (
+	TypeAccess    = "access"
+	TypeRefresh   = "refresh"
+	TypeTwoFactor = "2fa"
+)
+
+var (
+	ErrTokenEmpty            = errors.New("token is empty")
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenExpired          = errors.New("token is expired")
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenInvalidIssuer    = errors.New("token has invalid issuer")
+	ErrTokenInvalidAudience  = errors.New("token has invalid audience")
+	ErrTokenInvalidType      = errors.New("token has invalid type")
+	ErrTokenInvalid          = errors.New("token is invalid")
+)
+
+var validMethods = []string{AlgHS256, AlgRS256, AlgEdDSA}
+
 type UserInfoToken struct {
-	ID    string `json:"id" mapstructure:"user_id"`
-	Login string `json:"login"`
-	Name  string `json:"name"`
+	ID        string `json:"id" mapstructure:"user_id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+
+	// TokenID and ExpiresAt are filled from the registered claims by VerifyAccessToken.
+	TokenID   string    `json:"-" mapstructure:"-"`
+	ExpiresAt time.Time `json:"-" mapstructure:"-"`
+
+	// APIKeyID and Scopes are set when the request was authenticated with an
+	// API key instead of an access token.
+	APIKeyID string   `json:"-" mapstructure:"-"`
+	Scopes   []string `json:"-" mapstructure:"-"`
 }
 
-type jWTUserInfoClaims struct {
+type RefreshInfoToken struct {
+	UserGUID string
+	Family   string
+}
+
+type jWTClaims struct {
 	jwt.RegisteredClaims
-	User *UserInfoToken `json:"user,omitempty"`
+	Type   string         `json:"typ"`
+	User   *UserInfoToken `json:"user,omitempty"`
+	Family string         `json:"fam,omitempty"`
 }
 
-func NewToken(secret string, expirationAt time.Time, userInfo *UserInfoToken) (string, error) {
-	expiresIn := expirationAt.Sub(time.Now())
-	claims := jWTUserInfoClaims{
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-		userInfo,
+type Options struct {
+	Issuer   string
+	Audience []string
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// Generator issues and verifies access and refresh tokens signed with the
+// keys of a KeySet.
+type Generator struct {
+	keys *KeySet
+	opts Options
+}
+
+func NewGenerator(keys *KeySet, opts Options) *Generator {
+	return &Generator{
+		keys: keys,
+		opts: opts,
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
+func (g *Generator) NewAccessToken(expirationAt time.Time, userInfo *UserInfoToken) (string, error) {
+	return g.keys.sign(g.newClaims(TypeAccess, userInfo.ID, expirationAt, userInfo, ""))
+}
 
-	return tokenString, err
+// NewRefreshToken issues a refresh token for the user. Every refresh token
+// belongs to a family: all tokens obtained by rotating the one issued at login.
+func (g *Generator) NewRefreshToken(expirationAt time.Time, userGuid string, family string) (string, error) {
+	return g.keys.sign(g.newClaims(TypeRefresh, userGuid, expirationAt, nil, family))
 }
 
-func VerifyToken(secret string, token string) (*UserInfoToken, bool) {
-	if token == "" {
-		return nil, true
+// NewTwoFactorToken issues a short-lived token proving the password of the
+// user was checked. It is exchanged for a session together with a 2FA code.
+func (g *Generator) NewTwoFactorToken(expirationAt time.Time, userGuid string) (string, error) {
+	return g.keys.sign(g.newClaims(TypeTwoFactor, userGuid, expirationAt, nil, ""))
+}
+
+func (g *Generator) VerifyAccessToken(token string) (*UserInfoToken, error) {
+	claims, err := g.parse(token, TypeAccess)
+	if err != nil {
+		return nil, err
 	}
-	t, err := jwt.ParseWithClaims(
-		token,
-		&jWTUserInfoClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		},
-	)
 
-	userInfo, ok := t.Claims.(*jWTUserInfoClaims)
-	if !ok {
-		return nil, false
+	if claims.User == nil || claims.User.ID != claims.Subject {
+		return nil, ErrTokenInvalid
+	}
+
+	claims.User.TokenID = claims.ID
+	claims.User.ExpiresAt = claims.ExpiresAt.Time
+
+	return claims.User, nil
+}
+
+func (g *Generator) VerifyRefreshToken(token string) (*RefreshInfoToken, error) {
+	claims, err := g.parse(token, TypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" || claims.Family == "" {
+		return nil, ErrTokenInvalid
 	}
 
+	return &RefreshInfoToken{
+		UserGUID: claims.Subject,
+		Family:   claims.Family,
+	}, nil
+}
+
+// VerifyTwoFactorToken returns the guid of the user the token was issued for.
+func (g *Generator) VerifyTwoFactorToken(token string) (string, error) {
+	claims, err := g.parse(token, TypeTwoFactor)
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return userInfo.User, false
-		}
-		return nil, false
+		return "", err
+	}
+
+	if claims.Subject == "" {
+		return "", ErrTokenInvalid
+	}
+
+	return claims.Subject, nil
+}
+
+func (g *Generator) newClaims(
+	tokenType string,
+	subject string,
+	expirationAt time.Time,
+	userInfo *UserInfoToken,
+	family string,
+) jWTClaims {
+	timeNow := time.Now()
+
+	return jWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    g.opts.Issuer,
+			Subject:   subject,
+			Audience:  g.opts.Audience,
+			ExpiresAt: jwt.NewNumericDate(expirationAt),
+			NotBefore: jwt.NewNumericDate(timeNow),
+			IssuedAt:  jwt.NewNumericDate(timeNow),
+		},
+		Type:   tokenType,
+		User:   userInfo,
+		Family: family,
+	}
+}
+
+func (g *Generator) parse(token string, tokenType string) (*jWTClaims, error) {
+	if token == "" {
+		return nil, ErrTokenEmpty
 	}
 
-	expTime, err := t.Claims.GetExpirationTime()
-	fmt.Println("expTime", expTime)
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(g.opts.Leeway),
+	}
+	if g.opts.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(g.opts.Issuer))
+	}
+	if len(g.opts.Audience) > 0 {
+		// The token must be meant for this service, which is the first audience.
+		parserOptions = append(parserOptions, jwt.WithAudience(g.opts.Audience[0]))
+	}
+
+	claims := &jWTClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, g.keys.keyFunc, parserOptions...)
 	if err != nil {
-		return nil, false
+		return nil, verificationError(err)
+	}
+
+	if claims.Type != tokenType {
+		return nil, ErrTokenInvalidType
 	}
 
-	if !t.Valid || expTime.Before(time.Now()) {
-		return nil, false
+	return claims, nil
+}
+
+func verificationError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenSignatureInvalid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenInvalidIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenInvalidAudience
+	default:
+		return ErrTokenInvalid
 	}
+}
 
-	return userInfo.User, true
+// HashToken returns the hex encoded SHA-256 of a token, suitable for storage.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,265 @@
+package tokenGen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+var ErrUnsupportedAlgorithm = fmt.Errorf("unsupported signing algorithm")
+var ErrNoSigningKey = fmt.Errorf("no signing key")
+
+// SigningKey is a key identified by its kid. HS256 keys hold a shared
+// secret, RS256 and EdDSA keys hold a private key whose public part may be
+// published through JWKS.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func NewHMACKey(id string, secret string) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Algorithm: AlgHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// GenerateKey creates a new asymmetric key with a random kid.
+func GenerateKey(alg string) (*SigningKey, error) {
+	key := &SigningKey{
+		ID:        uuid.New().String(),
+		Algorithm: alg,
+		CreatedAt: time.Now(),
+	}
+
+	switch alg {
+	case AlgRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = private, &private.PublicKey
+	case AlgEdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = private, public
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	return key, nil
+}
+
+// ParseKey restores an asymmetric key stored with MarshalPrivateKey.
+func ParseKey(id string, alg string, privatePEM []byte, createdAt time.Time) (*SigningKey, error) {
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: invalid PEM", id)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+
+	key := &SigningKey{
+		ID:        id,
+		Algorithm: alg,
+		CreatedAt: createdAt,
+	}
+
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if alg != AlgRS256 {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		key.signKey, key.verifyKey = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		if alg != AlgEdDSA {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		key.signKey, key.verifyKey = k, k.Public()
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	return key, nil
+}
+
+// MarshalPrivateKey encodes an asymmetric private key as PKCS #8 PEM.
+func (k *SigningKey) MarshalPrivateKey() ([]byte, error) {
+	if k.Algorithm == AlgHS256 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.signKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// JWK is a public key in the RFC 7517 format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *SigningKey) jwk() (JWK, bool) {
+	jwk := JWK{Use: "sig", Kid: k.ID, Alg: k.Algorithm}
+
+	switch public := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		// Shared secrets are never published.
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still verified with. It is safe for concurrent use and may be replaced at
+// runtime when keys are rotated.
+type KeySet struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeySet(active *SigningKey, keys ...*SigningKey) *KeySet {
+	ks := &KeySet{}
+	ks.Set(active, keys...)
+	return ks
+}
+
+// Set replaces the active key and the verification keys. The active key is
+// always usable for verification.
+func (ks *KeySet) Set(active *SigningKey, keys ...*SigningKey) {
+	byID := make(map[string]*SigningKey, len(keys)+1)
+	for _, key := range keys {
+		byID[key.ID] = key
+	}
+	if active != nil {
+		byID[active.ID] = active
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.active = active
+	ks.keys = byID
+}
+
+func (ks *KeySet) Active() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.active
+}
+
+func (ks *KeySet) lookup(id string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.keys[id]
+	return key, ok
+}
+
+// JWKS returns the public part of every asymmetric verification key.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if jwk, ok := key.jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	key := ks.Active()
+	if key == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.signKey)
+}
+
+// keyFunc picks the verification key by the kid header and refuses tokens
+// whose algorithm does not match the key.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+
+	return key.verifyKey, nil
+}
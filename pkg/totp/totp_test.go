@@ -0,0 +1,146 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of RFC 6238 Appendix B, "12345678901234567890"
+// in ASCII, base32 encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// The test vectors of RFC 6238 Appendix B for SHA1. The RFC gives 8 digit
+// codes; the last Digits of them are the 6 digit codes.
+var rfcVectors = []struct {
+	unix int64
+	step int64
+	code string
+}{
+	{59, 0x1, "94287082"},
+	{1111111109, 0x23523EC, "07081804"},
+	{1111111111, 0x23523ED, "14050471"},
+	{1234567890, 0x273EF07, "89005924"},
+	{2000000000, 0x3F940AA, "69279037"},
+	{20000000000, 0x27BC86AA, "65353130"},
+}
+
+func TestStep(t *testing.T) {
+	for _, v := range rfcVectors {
+		got := Step(time.Unix(v.unix, 0))
+		if got != v.step {
+			t.Errorf("Step(%d) = %#x, want %#x", v.unix, got, v.step)
+		}
+	}
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, v.step)
+		if err != nil {
+			t.Fatalf("Code(step %#x): %v", v.step, err)
+		}
+		want := v.code[len(v.code)-Digits:]
+		if got != want {
+			t.Errorf("Code(step %#x) = %s, want %s", v.step, got, want)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	upper, err := Code(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upper != lower {
+		t.Errorf("lowercase secret gives %s, want %s", lower, upper)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	_, err := Code("not base32!", 1)
+	if err == nil {
+		t.Error("Code accepted a secret that is not base32")
+	}
+}
+
+func TestValidateRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		code := v.code[len(v.code)-Digits:]
+		step, ok := Validate(rfcSecret, code, time.Unix(v.unix, 0), 0)
+		if !ok || step != v.step {
+			t.Errorf("Validate(%s at %d) = %#x, %v, want %#x, true", code, v.unix, step, ok, v.step)
+		}
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	const skew = 1
+	const current = int64(0x23523ED)
+	now := time.Unix(current*int64(Period.Seconds()), 0)
+
+	for _, tc := range []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"one step behind", -skew, true},
+		{"one step ahead", skew, true},
+		{"two steps behind", -skew - 1, false},
+		{"two steps ahead", skew + 1, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, current+tc.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := Validate(rfcSecret, code, now, skew)
+			if ok != tc.ok {
+				t.Fatalf("Validate ok = %v, want %v", ok, tc.ok)
+			}
+			if ok && step != current+tc.offset {
+				t.Errorf("Validate step = %#x, want %#x", step, current+tc.offset)
+			}
+		})
+	}
+}
+
+func TestValidateStepEdges(t *testing.T) {
+	const step = int64(0x23523ED)
+	code, err := Code(rfcSecret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := time.Unix(step*int64(Period.Seconds()), 0)
+	last := first.Add(Period - time.Second)
+	for _, at := range []time.Time{first, last} {
+		if _, ok := Validate(rfcSecret, code, at, 0); !ok {
+			t.Errorf("code refused at %d, inside its step", at.Unix())
+		}
+	}
+	for _, at := range []time.Time{first.Add(-time.Second), last.Add(time.Second)} {
+		if _, ok := Validate(rfcSecret, code, at, 0); ok {
+			t.Errorf("code accepted at %d, outside its step without skew", at.Unix())
+		}
+	}
+}
+
+func TestValidateMalformedCode(t *testing.T) {
+	code, err := Code(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(59, 0)
+
+	for _, bad := range []string{"", code[:Digits-1], code + "0", "94287082"} {
+		if _, ok := Validate(rfcSecret, bad, at, 1); ok {
+			t.Errorf("Validate accepted %q", bad)
+		}
+	}
+}